@@ -0,0 +1,97 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+)
+
+// Call invokes registered method in-process. It goes through the same
+// middleware and params validation as requests served over HTTP, params are
+// passed as is when their type matches method arguments or converted
+// through JSON otherwise. Hooks aren't called, since there is no request
+// envelope. Reply must be a pointer, it can be nil when result isn't needed.
+func (s *RPC) Call(ctx context.Context, method string, params, reply interface{}) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	defer func() { // catch internal errors:
+		if rec := recover(); rec != nil {
+			err = &codec.Error{
+				Code:    codec.ErrInternal,
+				Message: "something went wrong",
+				Data:    rec,
+			}
+		}
+	}()
+
+	caller, err := s.get(method)
+	if err != nil {
+		return err
+	}
+
+	args, err := convert(params, caller.argsType)
+	if err != nil {
+		return &codec.Error{
+			Code:     codec.ErrBadParams,
+			Message:  err.Error(),
+			Data:     params,
+			Internal: err,
+		}
+	}
+
+	call := &Call{
+		Context: ctx,
+		Method:  method,
+		Args:    args.Interface(),
+		Reply:   reflect.New(caller.replyType).Interface(),
+	}
+
+	if err = caller.wrapped(call); err != nil || reply == nil {
+		return err
+	}
+
+	res, err := convert(call.Reply, reflect.TypeOf(reply))
+	if err != nil {
+		return &codec.Error{
+			Code:     codec.ErrInternal,
+			Message:  err.Error(),
+			Internal: err,
+		}
+	}
+	reflect.ValueOf(reply).Elem().Set(res.Elem())
+	return nil
+}
+
+// convert returns value of type t holding v. Values that can't be assigned
+// directly are converted through JSON, the same way as request params are.
+func convert(v interface{}, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	} else if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Type().AssignableTo(t) {
+		return rv.Elem(), nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	res := reflect.New(t)
+	if t.Kind() == reflect.Ptr {
+		res.Elem().Set(reflect.New(t.Elem()))
+	}
+
+	if err = json.Unmarshal(data, res.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return res.Elem(), nil
+}
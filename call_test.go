@@ -0,0 +1,111 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	ctxKey struct{}
+
+	PointArgs struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+)
+
+func TestCallSuite(t *testing.T) {
+	t.Run("Call test suite", func(t *testing.T) {
+		t.Run("should call method with typed params", func(t *testing.T) {
+			var (
+				srv   = NewRPC()
+				reply int
+			)
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			require.NoError(t, srv.Call(context.Background(), "sum", []int{1, 2, 3}, &reply))
+			require.Equal(t, 6, reply)
+		})
+
+		t.Run("should convert params and reply", func(t *testing.T) {
+			var (
+				srv   = NewRPC()
+				reply int64
+			)
+			require.NoError(t, srv.AddMethod("mul", func(ctx context.Context, args PointArgs, reply *int) error {
+				*reply = args.X * args.Y
+				return nil
+			}))
+
+			err := srv.Call(context.Background(), "mul", map[string]int{"x": 2, "y": 3}, &reply)
+			require.NoError(t, err)
+			require.EqualValues(t, 6, reply)
+		})
+
+		t.Run("should pass context to methods", func(t *testing.T) {
+			var (
+				srv   = NewRPC()
+				ctx   = context.WithValue(context.Background(), ctxKey{}, "value")
+				reply string
+			)
+			require.NoError(t, srv.AddMethod("ctx", func(ctx context.Context, args struct{}, reply *string) error {
+				*reply = ctx.Value(ctxKey{}).(string)
+				return nil
+			}))
+			require.NoError(t, srv.AddMethod("req", func(r *http.Request, args struct{}, reply *string) error {
+				// Handlers written for HTTP can read request URL and headers.
+				*reply = r.URL.Path + r.Header.Get("X-Test") + r.Context().Value(ctxKey{}).(string)
+				return nil
+			}))
+
+			require.NoError(t, srv.Call(ctx, "ctx", nil, &reply))
+			require.Equal(t, "value", reply)
+
+			reply = ""
+			require.NoError(t, srv.Call(ctx, "req", nil, &reply))
+			require.Equal(t, "value", reply)
+		})
+
+		t.Run("should go through middleware", func(t *testing.T) {
+			var (
+				srv  = NewRPC()
+				seen string
+			)
+			srv.Use(func(next Handler) Handler {
+				return func(call *Call) error {
+					seen = call.Method
+					return next(call)
+				}
+			})
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			require.NoError(t, srv.Call(context.Background(), "sum", []int{1}, nil))
+			require.Equal(t, "sum", seen)
+		})
+
+		t.Run("should fail", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+			require.NoError(t, srv.AddMethod("panic", func(ctx context.Context, args []int, reply *int) error {
+				panic("panic error")
+			}))
+
+			err := srv.Call(context.Background(), "unknown", nil, nil)
+			require.IsType(t, (*codec.Error)(nil), err)
+			require.Equal(t, codec.ErrNoMethod, err.(*codec.Error).Code)
+
+			err = srv.Call(context.Background(), "sum", "a", nil)
+			require.IsType(t, (*codec.Error)(nil), err)
+			require.Equal(t, codec.ErrBadParams, err.(*codec.Error).Code)
+
+			err = srv.Call(context.Background(), "panic", nil, nil)
+			require.IsType(t, (*codec.Error)(nil), err)
+			require.Equal(t, codec.ErrInternal, err.(*codec.Error).Code)
+			require.Equal(t, "panic error", err.(*codec.Error).Data)
+		})
+	})
+}
@@ -0,0 +1,379 @@
+// Package cbor implements CBOR codec, it has the same envelope as JSON-RPC
+// 2.0 with members encoded as CBOR map. Byte slices are encoded as CBOR
+// byte strings.
+package cbor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/misc"
+)
+
+type (
+	// serverRequest represents a request received by the server.
+	serverRequest struct {
+		Version string          `cbor:"jsonrpc"`
+		ID      interface{}     `cbor:"id"`
+		Method  string          `cbor:"method"`
+		Params  cbor.RawMessage `cbor:"params"`
+	}
+
+	// serverResponse represents a response returned by the server.
+	serverResponse struct {
+		Version string       `cbor:"jsonrpc"`
+		ID      interface{}  `cbor:"id"`
+		Result  interface{}  `cbor:"result,omitempty"`
+		Error   *codec.Error `cbor:"error,omitempty"`
+	}
+
+	// outgoingRequest represents a request sent by the server.
+	outgoingRequest struct {
+		Version string      `cbor:"jsonrpc"`
+		ID      *uint64     `cbor:"id,omitempty"`
+		Method  string      `cbor:"method"`
+		Params  interface{} `cbor:"params,omitempty"`
+	}
+
+	// clientResponse represents a response received by the server.
+	clientResponse struct {
+		ID     interface{}     `cbor:"id"`
+		Method string          `cbor:"method"`
+		Result cbor.RawMessage `cbor:"result"`
+		Error  *codec.Error    `cbor:"error"`
+	}
+
+	// Option configures codec.
+	Option func(*cborCodec)
+
+	// cborCodec creates a Request to process each request.
+	cborCodec struct {
+		encSel codec.EncoderSelector
+		enc    cbor.EncMode
+	}
+
+	// request decodes a single request and encodes its response.
+	request struct {
+		writer   http.ResponseWriter // nil for requests decoded from messages
+		send     func([]byte)
+		enc      cbor.EncMode
+		request  *serverRequest
+		envelope *codec.Envelope
+		encoder  codec.Encoder
+	}
+
+	// response is a response to the request sent by the server.
+	response struct {
+		id  uint64
+		res *clientResponse
+	}
+)
+
+// Canonical makes codec encode messages deterministically, as specified by
+// RFC 7049 canonical CBOR: map keys are sorted and the shortest form of
+// integers and lengths is used.
+func Canonical() Option {
+	return func(c *cborCodec) {
+		c.enc, _ = cbor.CanonicalEncOptions().EncMode()
+	}
+}
+
+// NewCodec returns a new CBOR codec based on passed encoder selector, struct
+// fields are encoded using json tags when there are no cbor ones.
+func NewCodec(encSel codec.EncoderSelector, opts ...Option) codec.Interface {
+	enc, _ := cbor.EncOptions{}.EncMode()
+	c := &cborCodec{encSel: encSel, enc: enc}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Binary implements codec.BinaryCodec, CBOR messages are binary.
+func (c *cborCodec) Binary() bool {
+	return true
+}
+
+// NewRequest returns a Request.
+func (c *cborCodec) NewRequest(w http.ResponseWriter, r *http.Request) (codec.Request, error) {
+	defer func() {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+	}()
+
+	if r.Method != http.MethodPost {
+		return nil, &codec.Error{
+			Code:    codec.ErrInvalidRequest,
+			Message: "rpc: POST method required, received " + r.Method,
+		}
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, newParseError(err)
+	}
+
+	req, err := c.newRequest(data)
+	if err != nil {
+		return nil, err
+	}
+	req.writer, req.encoder = w, c.encSel.Select(r)
+	return req, nil
+}
+
+// NewMessage decodes single request from msg, batches aren't supported.
+func (c *cborCodec) NewMessage(msg []byte, send func([]byte)) ([]codec.Request, error) {
+	req, err := c.newRequest(msg)
+	if err != nil {
+		data, _ := c.enc.Marshal(&serverResponse{Version: codec.Version, Error: toError(err)})
+		send(data)
+		return nil, err
+	}
+	req.send = send
+	return []codec.Request{req}, nil
+}
+
+// NewNotification encodes notification sent by the server.
+func (c *cborCodec) NewNotification(method string, params interface{}) ([]byte, error) {
+	return c.enc.Marshal(&outgoingRequest{
+		Version: codec.Version,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// NewCall encodes request sent by the server.
+func (c *cborCodec) NewCall(id uint64, method string, params interface{}) ([]byte, error) {
+	return c.enc.Marshal(&outgoingRequest{
+		Version: codec.Version,
+		ID:      &id,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// NewResponse decodes response to the request sent by the server.
+func (c *cborCodec) NewResponse(msg []byte) (codec.Response, bool) {
+	res := new(clientResponse)
+	if err := cbor.Unmarshal(msg, res); err != nil || res.Method != "" || (res.Result == nil && res.Error == nil) {
+		return nil, false
+	}
+
+	var id uint64
+	switch v := res.ID.(type) {
+	case uint64:
+		id = v
+	case int64:
+		if v < 0 {
+			return nil, false
+		}
+		id = uint64(v)
+	default:
+		return nil, false
+	}
+	return &response{id: id, res: res}, true
+}
+
+// NewResponder returns Responder writing CBOR responses to the request
+// decoded by other codec.
+func (c *cborCodec) NewResponder(w http.ResponseWriter, r *http.Request, env *codec.Envelope) (codec.Responder, error) {
+	req := &serverRequest{Version: codec.Version, Method: env.Method}
+	if env.ID != "" {
+		req.ID = env.IDValue()
+	}
+	return &request{
+		writer:   w,
+		enc:      c.enc,
+		request:  req,
+		envelope: env,
+		encoder:  c.encSel.Select(r),
+	}, nil
+}
+
+// newRequest returns request decoded from data.
+func (c *cborCodec) newRequest(data []byte) (*request, error) {
+	req := new(serverRequest)
+	if err := cbor.Unmarshal(data, req); err != nil {
+		return nil, newParseError(err)
+	} else if req.Version != codec.Version {
+		return nil, &codec.Error{
+			Code:    codec.ErrInvalidRequest,
+			Message: "jsonrpc must be " + codec.Version,
+		}
+	}
+
+	env := &codec.Envelope{
+		Version: req.Version,
+		Method:  req.Method,
+		Params:  req.Params,
+	}
+	if req.ID != nil {
+		env.ID = fmt.Sprint(req.ID)
+	}
+	return &request{
+		enc:      c.enc,
+		request:  req,
+		envelope: env,
+		encoder:  codec.DefaultEncoder,
+	}, nil
+}
+
+// newParseError returns error for request that can't be decoded.
+func newParseError(err error) error {
+	return &codec.Error{
+		Code:     codec.ErrParse,
+		Message:  err.Error(),
+		Internal: err,
+	}
+}
+
+// HandleError writes error response, true is returned when err isn't nil.
+func (c *request) HandleError(err error) bool {
+	switch err := err.(type) {
+	case nil:
+		return false
+	case *misc.HTTPError:
+		c.WriteError(err.Code, err)
+	case *codec.Error:
+		c.WriteError(err.Code, err)
+	default:
+		c.WriteError(http.StatusBadRequest, err)
+	}
+	return true
+}
+
+// Envelope returns the parsed envelope of the current request.
+func (c *request) Envelope() *codec.Envelope {
+	return c.envelope
+}
+
+// Method returns the RPC method for the current request.
+func (c *request) Method() string {
+	return c.envelope.Method
+}
+
+// ReadRequest fills the request object for the RPC method, params can be
+// passed by-position as array or by-name as map.
+func (c *request) ReadRequest(args interface{}) error {
+	if params := c.envelope.Params; params != nil {
+		if err := unmarshalParams(params, args); err != nil {
+			return &codec.Error{
+				Code:     codec.ErrBadParams,
+				Message:  err.Error(),
+				Internal: err,
+			}
+		}
+	}
+	return nil
+}
+
+// WriteResponse encodes the response and writes it.
+func (c *request) WriteResponse(reply interface{}) {
+	c.writeServerResponse(&serverResponse{
+		Version: codec.Version,
+		ID:      c.request.ID,
+		Result:  reply,
+	})
+}
+
+// WriteError encodes the error and writes it.
+func (c *request) WriteError(status int, err error) {
+	c.writeServerResponse(&serverResponse{
+		Version: codec.Version,
+		ID:      c.request.ID,
+		Error:   toError(err),
+	})
+}
+
+func (c *request) writeServerResponse(res *serverResponse) {
+	// ID is null for notifications and they don't have a response.
+	if res.ID == nil {
+		return
+	}
+
+	data, err := c.enc.Marshal(res)
+	if err != nil {
+		data, _ = c.enc.Marshal(&serverResponse{
+			Version: codec.Version,
+			ID:      res.ID,
+			Error: &codec.Error{
+				Code:    codec.ErrInternal,
+				Message: err.Error(),
+			},
+		})
+	}
+
+	if c.writer == nil {
+		c.send(data)
+		return
+	}
+
+	c.writer.Header().Set(misc.HeaderXContentTypeOptions, "nosniff")
+	c.writer.Header().Set(misc.HeaderContentType, misc.MIMEApplicationCBOR)
+	_ = codec.WriteEncoded(c.writer, c.encoder, data)
+}
+
+// ID of the request.
+func (r *response) ID() uint64 {
+	return r.id
+}
+
+// Err returns an error sent by the client.
+func (r *response) Err() error {
+	if r.res.Error == nil {
+		return nil
+	}
+	return r.res.Error
+}
+
+// ReadResult decodes the result into reply.
+func (r *response) ReadResult(reply interface{}) error {
+	return cbor.Unmarshal(r.res.Result, reply)
+}
+
+// toError converts err into codec.Error.
+func toError(err error) *codec.Error {
+	if e, ok := err.(*codec.Error); ok {
+		return e
+	}
+	return &codec.Error{
+		Code:    codec.ErrServer,
+		Message: err.Error(),
+	}
+}
+
+// unmarshalParams decodes params into args, array params are assigned to
+// exported fields of struct args in order of declaration.
+func unmarshalParams(params []byte, args interface{}) error {
+	err := cbor.Unmarshal(params, args)
+	if err == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(args)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return err
+	}
+
+	var items []cbor.RawMessage
+	if cbor.Unmarshal(params, &items) != nil {
+		return err
+	}
+
+	v = v.Elem()
+	for i, j := 0, 0; i < v.NumField() && j < len(items); i++ {
+		if v.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		if err := cbor.Unmarshal(items[j], v.Field(i).Addr().Interface()); err != nil {
+			return err
+		}
+		j++
+	}
+	return nil
+}
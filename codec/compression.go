@@ -1,92 +1,391 @@
 package codec
 
 import (
+	"bytes"
 	"compress/flate"
 	"compress/gzip"
+	"errors"
 	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
-	"unicode"
+	"sync"
 
 	"github.com/nspcc-dev/jsonrpc/misc"
 )
 
 type (
-	// CompressionSelector generates the compressed http encoder.
-	CompressionSelector struct{}
+	// Compressor returns writer compressing data written to w with the
+	// level, meaning of the level is specific to the compression.
+	Compressor func(w io.Writer, level int) (io.WriteCloser, error)
 
-	// gzipEncoder implements the gzip compressed http encoder.
-	gzipEncoder struct{}
+	// CompressionSelector selects compression of the response by
+	// Accept-Encoding header of the request. Zero value selects built-in
+	// gzip and deflate and is ready to use.
+	CompressionSelector struct {
+		mu      sync.RWMutex
+		items   []*compression // builtinCompressions when nil
+		prefer  []string       // server preference, client one is used when empty
+		minSize int            // responses shorter than this aren't compressed
+	}
+
+	// compression is a registered content-coding, it's not modified once
+	// registered.
+	compression struct {
+		name  string
+		fn    Compressor
+		level int
+		pool  sync.Pool // compressors which can be reset
+	}
+
+	// compressionEncoder implements the compressed http encoder.
+	compressionEncoder struct {
+		*compression
+		minSize int
+	}
+
+	// identityEncoder doesn't compress the response, but tells caches it
+	// depends on Accept-Encoding.
+	identityEncoder struct{}
 
-	gzipWriter struct {
-		w *gzip.Writer
+	// compressionWriter compresses the response, data is buffered until
+	// minSize is reached, so short responses are sent as is. Compressed data
+	// is buffered until the response is flushed, so Content-Length is set
+	// for responses written at once.
+	compressionWriter struct {
+		w       http.ResponseWriter
+		enc     *compressionEncoder
+		buf     []byte
+		out     *bytes.Buffer  // compressed data, nil once flushed
+		cw      io.WriteCloser // nil until compression starts
+		started bool
+		closed  bool
 	}
 
-	// deflateEncoder implements the deflate compressed http encoder.
-	deflateEncoder struct{}
+	// compressionSink receives compressed data of compressionWriter.
+	compressionSink compressionWriter
 
-	// deflateWriter writes and closes the deflate writer.
-	deflateWriter struct {
-		w *flate.Writer
+	// flusher is implemented by compressors which can flush pending data,
+	// e.g. gzip.Writer and flate.Writer.
+	flusher interface {
+		Flush() error
+	}
+
+	// resetter is implemented by compressors which can be reused, e.g.
+	// gzip.Writer and flate.Writer.
+	resetter interface {
+		io.WriteCloser
+		Reset(w io.Writer)
+	}
+
+	// acceptedEncoding is a content-coding of Accept-Encoding header.
+	acceptedEncoding struct {
+		q     float64
+		index int
 	}
 )
 
-func (gw *gzipWriter) Write(p []byte) (n int, err error) {
-	defer func() {
-		if err == nil {
-			err = gw.w.Close()
+// identity is content-coding of uncompressed response.
+const identity = "identity"
+
+// ErrWriterClosed returned when response is written after it's finished.
+var ErrWriterClosed = errors.New("rpc: response writer is closed")
+
+// builtinCompressions are used by zero value of CompressionSelector.
+var builtinCompressions = []*compression{
+	{name: "gzip", fn: GzipCompressor, level: gzip.DefaultCompression},
+	{name: "deflate", fn: DeflateCompressor, level: flate.DefaultCompression},
+}
+
+// GzipCompressor is Compressor of gzip content-coding.
+func GzipCompressor(w io.Writer, level int) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, level)
+}
+
+// DeflateCompressor is Compressor of deflate content-coding.
+func DeflateCompressor(w io.Writer, level int) (io.WriteCloser, error) {
+	return flate.NewWriter(w, level)
+}
+
+// NewCompressionSelector returns CompressionSelector with built-in gzip and
+// deflate registered.
+func NewCompressionSelector() *CompressionSelector {
+	return new(CompressionSelector)
+}
+
+// Register adds content-coding with the name, e.g. "br" or "zstd", which
+// is compressed by fn with the level. Registering existing name replaces
+// its compressor and level. Level is checked by creating a compressor, so
+// error is returned when fn doesn't accept it.
+func (s *CompressionSelector) Register(name string, fn Compressor, level int) error {
+	w, err := fn(ioutil.Discard, level)
+	if err != nil {
+		return err
+	}
+	_ = w.Close()
+
+	name = strings.ToLower(name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.items == nil {
+		s.items = append([]*compression(nil), builtinCompressions...)
+	}
+
+	item := &compression{name: name, fn: fn, level: level}
+	for i, c := range s.items {
+		if c.name == name {
+			s.items[i] = item
+			return nil
 		}
-	}()
-	return gw.w.Write(p)
+	}
+	s.items = append(s.items, item)
+	return nil
 }
 
-func (enc *gzipEncoder) Encode(w http.ResponseWriter) io.Writer {
-	w.Header().Set(misc.HeaderContentEncoding, "gzip")
-	return &gzipWriter{gzip.NewWriter(w)}
+// SetPreference sets server preference of content-codings, it breaks ties
+// between codings the client accepts with the same q-value. Without it the
+// client order is used.
+func (s *CompressionSelector) SetPreference(names ...string) {
+	prefer := make([]string, 0, len(names))
+	for _, name := range names {
+		prefer = append(prefer, strings.ToLower(name))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefer = prefer
 }
 
-func (fw *deflateWriter) Write(p []byte) (n int, err error) {
-	defer func() {
-		if err == nil {
-			err = fw.w.Close()
+// SetMinSize sets size of response starting from which it's compressed,
+// shorter responses aren't worth it. All responses are compressed by default.
+func (s *CompressionSelector) SetMinSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minSize = size
+}
+
+// Select method selects the compression encoder as specified by RFC 7231:
+// codings are weighted by q-values, "*" matches codings not listed, and
+// response isn't compressed when identity is preferred or nothing else is
+// acceptable.
+func (s *CompressionSelector) Select(r *http.Request) Encoder {
+	header, ok := r.Header[misc.HeaderAcceptEncoding]
+	if !ok {
+		return identityEncoder{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items, prefer, minSize := s.items, s.prefer, s.minSize
+	if items == nil {
+		items = builtinCompressions
+	}
+
+	var (
+		accepted      = parseAcceptEncoding(strings.Join(header, ","))
+		star, hasStar = accepted["*"]
+		best          *compression
+		bestAcc       acceptedEncoding
+		bestRank      int
+	)
+
+	rank := func(name string) int {
+		for i, p := range prefer {
+			if p == name {
+				return i
+			}
+		}
+		return len(prefer)
+	}
+
+	for _, c := range items {
+		acc, ok := accepted[c.name]
+		if !ok {
+			acc, ok = star, hasStar
+		}
+		if !ok || acc.q <= 0 {
+			continue
 		}
-	}()
-	return fw.w.Write(p)
-}
-
-func (enc *deflateEncoder) Encode(w http.ResponseWriter) io.Writer {
-	// we use default compression level, error can be omitted
-	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
-	w.Header().Set(misc.HeaderContentEncoding, "deflate")
-	return &deflateWriter{fw}
-}
-
-// acceptedEnc returns the first compression type in "Accept-Encoding" header
-// field of the request.
-func acceptedEnc(req *http.Request) string {
-	encHeader := req.Header.Get(misc.HeaderAcceptEncoding)
-	if encHeader == "" {
-		return ""
-	}
-	encTypes := strings.FieldsFunc(encHeader, func(r rune) bool {
-		return unicode.IsSpace(r) || r == ','
-	})
-	for _, enc := range encTypes {
-		if enc == "gzip" || enc == "deflate" {
-			return enc
+
+		if r := rank(c.name); best == nil || acc.q > bestAcc.q ||
+			acc.q == bestAcc.q && (r < bestRank || r == bestRank && acc.index < bestAcc.index) {
+			best, bestAcc, bestRank = c, acc, r
+		}
+	}
+
+	// Identity is acceptable unless it's excluded explicitly.
+	id, ok := accepted[identity]
+	if !ok {
+		id, ok = star, hasStar
+	}
+	if best == nil || ok && id.q > bestAcc.q {
+		return identityEncoder{}
+	}
+	return &compressionEncoder{compression: best, minSize: minSize}
+}
+
+// parseAcceptEncoding returns q-values and positions of content-codings of
+// Accept-Encoding header, malformed ones are skipped.
+func parseAcceptEncoding(header string) map[string]acceptedEncoding {
+	res := make(map[string]acceptedEncoding)
+	for i, item := range strings.Split(header, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		// Media type parser handles token with parameters the same way.
+		name, params, err := mime.ParseMediaType(item)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		res[name] = acceptedEncoding{q: q, index: i}
+	}
+	return res
+}
+
+// newWriter returns compressor writing to w, it's reused when possible.
+func (c *compression) newWriter(w io.Writer) (io.WriteCloser, error) {
+	if cw, ok := c.pool.Get().(resetter); ok {
+		cw.Reset(w)
+		return cw, nil
+	}
+	return c.fn(w, c.level)
+}
+
+// putWriter returns closed compressor to the pool when it can be reused,
+// it's detached from the response, so the response isn't kept in memory.
+func (c *compression) putWriter(cw io.WriteCloser) {
+	if r, ok := cw.(resetter); ok {
+		r.Reset(ioutil.Discard)
+		c.pool.Put(r)
+	}
+}
+
+// Encode returns writer compressing the response, it must be closed with
+// CloseEncoded when the response is written.
+func (enc *compressionEncoder) Encode(w http.ResponseWriter) io.Writer {
+	w.Header().Add(misc.HeaderVary, misc.HeaderAcceptEncoding)
+	return &compressionWriter{w: w, enc: enc}
+}
+
+// Encode returns w as is.
+func (identityEncoder) Encode(w http.ResponseWriter) io.Writer {
+	w.Header().Add(misc.HeaderVary, misc.HeaderAcceptEncoding)
+	return w
+}
+
+// Write buffers p until minSize is reached, then compresses it.
+func (c *compressionWriter) Write(p []byte) (int, error) {
+	if c.closed {
+		return 0, ErrWriterClosed
+	} else if !c.started {
+		if len(c.buf)+len(p) < c.enc.minSize {
+			c.buf = append(c.buf, p...)
+			return len(p), nil
+		} else if err := c.start(); err != nil {
+			return 0, err
+		}
+	}
+	return c.cw.Write(p)
+}
+
+// start sets headers of compressed response and compresses buffered data.
+// When compressor can't be created, response isn't compressed.
+func (c *compressionWriter) start() error {
+	c.started = true
+
+	var err error
+	if c.cw, err = c.enc.newWriter((*compressionSink)(c)); err != nil {
+		c.cw = nil
+		return c.writeBuffered()
+	}
+
+	c.out = getBuffer()
+	c.w.Header().Set(misc.HeaderContentEncoding, c.enc.name)
+
+	if len(c.buf) > 0 {
+		_, err = c.cw.Write(c.buf)
+	}
+	c.buf = nil
+	return err
+}
+
+// writeBuffered writes buffered data as is, all the next writes are passed
+// to the response directly.
+func (c *compressionWriter) writeBuffered() error {
+	c.cw = nopCloser{c.w}
+	_, err := c.w.Write(c.buf)
+	c.buf = nil
+	return err
+}
+
+// Flush implements http.Flusher, compression is started if it wasn't, so
+// streamed responses are compressed regardless of size of their parts.
+func (c *compressionWriter) Flush() {
+	if c.closed {
+		return
+	} else if !c.started && c.start() != nil {
+		return
+	}
+
+	if f, ok := c.cw.(flusher); ok {
+		_ = f.Flush()
+	}
+	if c.out != nil {
+		// Response is streamed from now on, its length is unknown.
+		c.w.Header().Del(misc.HeaderContentLength)
+		_, _ = c.w.Write(c.out.Bytes())
+		putBuffer(c.out)
+		c.out = nil
+	}
+	if f, ok := c.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes compressed stream, response shorter than minSize is
+// written as is. Only the first call has an effect.
+func (c *compressionWriter) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if !c.started {
+		c.started = true
+		c.w.Header().Set(misc.HeaderContentLength, strconv.Itoa(len(c.buf)))
+		return c.writeBuffered()
+	}
+
+	err := c.cw.Close()
+	c.enc.putWriter(c.cw)
+	if c.out != nil {
+		if err == nil {
+			c.w.Header().Set(misc.HeaderContentLength, strconv.Itoa(c.out.Len()))
+			_, err = c.w.Write(c.out.Bytes())
 		}
+		putBuffer(c.out)
+		c.out = nil
 	}
-	return ""
+	return err
 }
 
-// Select method selects the correct compression encoder based on http HEADER.
-func (*CompressionSelector) Select(r *http.Request) Encoder {
-	switch enc := acceptedEnc(r); enc {
-	case "gzip":
-		return &gzipEncoder{}
-	case "deflate":
-		return &deflateEncoder{}
-	default:
-		return DefaultEncoder
+// Write writes compressed data to the buffer or to the response once it's
+// flushed.
+func (s *compressionSink) Write(p []byte) (int, error) {
+	if s.out != nil {
+		return s.out.Write(p)
 	}
+	return s.w.Write(p)
 }
@@ -0,0 +1,232 @@
+package codec
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/stretchr/testify/require"
+)
+
+// nopCompressor doesn't compress, it fails on negative levels.
+type nopCompressor struct {
+	io.Writer
+}
+
+func (nopCompressor) Close() error { return nil }
+
+func newNopCompressor(w io.Writer, level int) (io.WriteCloser, error) {
+	if level < 0 {
+		return nil, errors.New("bad level")
+	}
+	return nopCompressor{w}, nil
+}
+
+// selectEncoding returns Content-Encoding chosen for Accept-Encoding header,
+// nil header means it's missing.
+func selectEncoding(t *testing.T, sel EncoderSelector, header *string) string {
+	req, err := http.NewRequest(http.MethodPost, "/", nil)
+	require.NoError(t, err)
+	if header != nil {
+		req.Header.Set(misc.HeaderAcceptEncoding, *header)
+	}
+
+	rec := httptest.NewRecorder()
+	w := sel.Select(req).Encode(rec)
+	_, err = w.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, CloseEncoded(w))
+	require.Equal(t, misc.HeaderAcceptEncoding, rec.Header().Get(misc.HeaderVary))
+	return rec.Header().Get(misc.HeaderContentEncoding)
+}
+
+func TestCompressionSuite(t *testing.T) {
+	t.Run("Compression selector test suite", func(t *testing.T) {
+		t.Run("should negotiate built-in codings", func(t *testing.T) {
+			require.Equal(t, "", selectEncoding(t, new(CompressionSelector), nil))
+
+			for header, enc := range map[string]string{
+				"":                              "",
+				"gzip":                          "gzip",
+				"deflate, gzip":                 "deflate",
+				"GZIP;q=0.5, deflate;q=0.8":     "deflate",
+				"gzip;q=0, deflate;q=0":         "",
+				"br, *;q=0.1":                   "gzip",
+				"*;q=0.5, gzip;q=0":             "deflate",
+				"identity, gzip;q=0.5":          "",
+				"identity;q=0, *":               "gzip",
+				"gzip;q=1.5, deflate;q=bad, br": "",
+				"wrong, encoding":               "",
+			} {
+				header := header
+				require.Equal(t, enc, selectEncoding(t, new(CompressionSelector), &header), header)
+			}
+		})
+
+		t.Run("should use registered codings", func(t *testing.T) {
+			sel := NewCompressionSelector()
+			require.Error(t, sel.Register("br", newNopCompressor, -1))
+			require.NoError(t, sel.Register("BR", newNopCompressor, 5))
+
+			header := "gzip;q=0.9, br"
+			require.Equal(t, "br", selectEncoding(t, sel, &header))
+
+			header = "*"
+			require.Equal(t, "gzip", selectEncoding(t, sel, &header))
+
+			sel.SetPreference("br", "gzip")
+			header = "gzip, deflate, br"
+			require.Equal(t, "br", selectEncoding(t, sel, &header))
+
+			header = "deflate, gzip"
+			require.Equal(t, "gzip", selectEncoding(t, sel, &header))
+		})
+
+		t.Run("should configure zero value", func(t *testing.T) {
+			sel := new(CompressionSelector)
+			require.NotPanics(t, func() {
+				require.NoError(t, sel.Register("br", newNopCompressor, 5))
+				sel.SetPreference("br")
+				sel.SetMinSize(1)
+			})
+
+			header := "gzip, br"
+			require.Equal(t, "br", selectEncoding(t, sel, &header))
+
+			header = "gzip, deflate"
+			require.Equal(t, "gzip", selectEncoding(t, sel, &header))
+
+			// Built-in codings aren't changed.
+			header = "br"
+			require.Equal(t, "", selectEncoding(t, new(CompressionSelector), &header))
+		})
+
+		t.Run("should compress with registered level", func(t *testing.T) {
+			sel := NewCompressionSelector()
+			require.Error(t, sel.Register("gzip", GzipCompressor, 100))
+			require.NoError(t, sel.Register("gzip", GzipCompressor, gzip.BestSpeed))
+
+			req, err := http.NewRequest(http.MethodPost, "/", nil)
+			require.NoError(t, err)
+			req.Header.Set(misc.HeaderAcceptEncoding, "gzip")
+
+			rec := httptest.NewRecorder()
+			w := sel.Select(req).Encode(rec)
+			_, err = w.Write([]byte("data"))
+			require.NoError(t, err)
+			require.NoError(t, CloseEncoded(w))
+
+			gz, err := gzip.NewReader(rec.Body)
+			require.NoError(t, err)
+
+			data := make([]byte, 4)
+			_, err = io.ReadFull(gz, data)
+			require.NoError(t, err)
+			require.Equal(t, "data", string(data))
+		})
+
+		t.Run("should write single stream across writes", func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/", nil)
+			require.NoError(t, err)
+			req.Header.Set(misc.HeaderAcceptEncoding, "gzip")
+
+			rec := httptest.NewRecorder()
+			w := new(CompressionSelector).Select(req).Encode(rec)
+			for _, part := range []string{"one", "two", "three"} {
+				_, err = w.Write([]byte(part))
+				require.NoError(t, err)
+			}
+
+			w.(http.Flusher).Flush()
+			require.True(t, rec.Flushed)
+
+			require.NoError(t, CloseEncoded(w))
+			require.NoError(t, CloseEncoded(w))
+			_, err = w.Write([]byte("four"))
+			require.Equal(t, ErrWriterClosed, err)
+
+			gz, err := gzip.NewReader(rec.Body)
+			require.NoError(t, err)
+			gz.Multistream(false)
+
+			data, err := ioutil.ReadAll(gz)
+			require.NoError(t, err)
+			require.Equal(t, "onetwothree", string(data))
+			require.Zero(t, rec.Body.Len())
+		})
+
+		t.Run("should skip compression below minimal size", func(t *testing.T) {
+			sel := NewCompressionSelector()
+			sel.SetMinSize(8)
+
+			req, err := http.NewRequest(http.MethodPost, "/", nil)
+			require.NoError(t, err)
+			req.Header.Set(misc.HeaderAcceptEncoding, "gzip")
+
+			for body, enc := range map[string]string{
+				"short":    "",
+				"long one": "gzip",
+			} {
+				rec := httptest.NewRecorder()
+				w := sel.Select(req).Encode(rec)
+				for _, b := range []byte(body) {
+					_, err = w.Write([]byte{b})
+					require.NoError(t, err)
+				}
+				require.NoError(t, CloseEncoded(w))
+				require.Equal(t, enc, rec.Header().Get(misc.HeaderContentEncoding), body)
+
+				if enc == "" {
+					require.Equal(t, body, rec.Body.String())
+					continue
+				}
+
+				gz, err := gzip.NewReader(rec.Body)
+				require.NoError(t, err)
+				data, err := ioutil.ReadAll(gz)
+				require.NoError(t, err)
+				require.Equal(t, body, string(data))
+			}
+		})
+
+		t.Run("should set Content-Length of whole response", func(t *testing.T) {
+			sel := NewCompressionSelector()
+			sel.SetMinSize(8)
+
+			req, err := http.NewRequest(http.MethodPost, "/", nil)
+			require.NoError(t, err)
+			req.Header.Set(misc.HeaderAcceptEncoding, "gzip")
+
+			for _, body := range []string{"short", strings.Repeat("long one", 100)} {
+				// Compressors are reused, so each response is checked twice.
+				for i := 0; i < 2; i++ {
+					rec := httptest.NewRecorder()
+					require.NoError(t, WriteEncoded(rec, sel.Select(req), []byte(body)))
+					require.Equal(t, strconv.Itoa(rec.Body.Len()), rec.Header().Get(misc.HeaderContentLength))
+
+					if rec.Header().Get(misc.HeaderContentEncoding) == "" {
+						require.Equal(t, body, rec.Body.String())
+						continue
+					}
+
+					gz, err := gzip.NewReader(rec.Body)
+					require.NoError(t, err)
+					data, err := ioutil.ReadAll(gz)
+					require.NoError(t, err)
+					require.Equal(t, body, string(data))
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			require.NoError(t, WriteEncoded(rec, DefaultEncoder, []byte("data")))
+			require.Equal(t, "4", rec.Header().Get(misc.HeaderContentLength))
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package codec
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
+)
+
+type (
+	// decompressedBody reads decompressed request body and closes both the
+	// decompressor and the original body.
+	decompressedBody struct {
+		io.Reader
+		zr   io.Closer
+		body io.Closer
+	}
+
+	// limitedReader fails with ErrBodyTooLarge when more than n bytes are
+	// read, unlike io.LimitedReader which silently stops.
+	limitedReader struct {
+		r io.Reader
+		n int64
+	}
+)
+
+// DefaultDecompressedLimit is the default limit of decompressed request body.
+const DefaultDecompressedLimit = 32 << 20
+
+// ErrBodyTooLarge returned when decompressed request body exceeds the limit.
+var ErrBodyTooLarge = errors.New("rpc: decompressed request body is too large")
+
+// DecompressBody replaces body of request sent with "gzip" or "deflate"
+// Content-Encoding with its decompressed stream, reading more than limit
+// bytes of it fails with ErrBodyTooLarge. Request without Content-Encoding
+// is left as is, unknown encodings are rejected.
+func DecompressBody(r *http.Request, limit int64) error {
+	enc := strings.ToLower(strings.TrimSpace(r.Header.Get(misc.HeaderContentEncoding)))
+	if enc == "" || enc == "identity" || r.Body == nil {
+		return nil
+	}
+
+	var (
+		br  = bufio.NewReader(r.Body)
+		zr  io.ReadCloser
+		err error
+	)
+
+	switch enc {
+	case "gzip", "x-gzip":
+		zr, err = gzip.NewReader(br)
+	case "deflate":
+		// Deflate is meant to be zlib stream, but some clients send raw
+		// deflate data, as our encoder does.
+		if isZlib(br) {
+			zr, err = zlib.NewReader(br)
+		} else {
+			zr = flate.NewReader(br)
+		}
+	default:
+		return misc.NewHTTPError(http.StatusUnsupportedMediaType, "rpc: unsupported Content-Encoding: "+enc)
+	}
+
+	if err != nil {
+		return misc.NewHTTPError(http.StatusBadRequest, "rpc: malformed request body: "+err.Error())
+	}
+
+	r.Body = &decompressedBody{
+		Reader: &limitedReader{r: zr, n: limit},
+		zr:     zr,
+		body:   r.Body,
+	}
+	r.Header.Del(misc.HeaderContentEncoding)
+	r.ContentLength = -1
+	return nil
+}
+
+// isZlib reports whether buffered stream starts with zlib header.
+func isZlib(br *bufio.Reader) bool {
+	h, err := br.Peek(2)
+	if err != nil {
+		return false
+	}
+	// Compression method must be deflate and header checksum must match.
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
+
+// Close closes decompressor and the original body.
+func (b *decompressedBody) Close() error {
+	_ = b.zr.Close()
+	return b.body.Close()
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrBodyTooLarge
+	} else if int64(len(p)) > l.n+1 {
+		// Read one byte more than allowed to tell the exact limit from
+		// the exceeded one.
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+	if l.n -= int64(n); l.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	return n, err
+}
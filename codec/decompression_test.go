@@ -0,0 +1,109 @@
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, enc string, data []byte) []byte {
+	var (
+		buf = new(bytes.Buffer)
+		w   io.WriteCloser
+		err error
+	)
+
+	switch enc {
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "zlib":
+		w = zlib.NewWriter(buf)
+	case "flate":
+		w, err = flate.NewWriter(buf, flate.DefaultCompression)
+		require.NoError(t, err)
+	}
+
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func newCompressedRequest(t *testing.T, enc string, body []byte) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set(misc.HeaderContentEncoding, enc)
+	return req
+}
+
+func TestDecompressionSuite(t *testing.T) {
+	t.Run("Request decompression test suite", func(t *testing.T) {
+		data := []byte(strings.Repeat(`{"jsonrpc": "2.0", "method": "ping"}`, 100))
+
+		t.Run("should decompress body", func(t *testing.T) {
+			for enc, format := range map[string]string{
+				"gzip":    "gzip",
+				"x-gzip":  "gzip",
+				"deflate": "zlib",
+				"DEFLATE": "flate",
+			} {
+				req := newCompressedRequest(t, enc, compress(t, format, data))
+				require.NoError(t, DecompressBody(req, int64(len(data))), enc)
+				require.Empty(t, req.Header.Get(misc.HeaderContentEncoding))
+
+				body, err := ioutil.ReadAll(req.Body)
+				require.NoError(t, err, enc)
+				require.Equal(t, data, body, enc)
+				require.NoError(t, req.Body.Close())
+			}
+		})
+
+		t.Run("should leave plain body as is", func(t *testing.T) {
+			for _, enc := range []string{"", "identity"} {
+				req := newCompressedRequest(t, enc, data)
+				body := req.Body
+				require.NoError(t, DecompressBody(req, 1))
+				require.Equal(t, body, req.Body)
+			}
+		})
+
+		t.Run("should fail when body exceeds limit", func(t *testing.T) {
+			req := newCompressedRequest(t, "gzip", compress(t, "gzip", data))
+			require.NoError(t, DecompressBody(req, int64(len(data))-1))
+
+			_, err := ioutil.ReadAll(req.Body)
+			require.Equal(t, ErrBodyTooLarge, err)
+		})
+
+		t.Run("should reject bad encoding", func(t *testing.T) {
+			err := DecompressBody(newCompressedRequest(t, "br", data), 1)
+			require.Equal(t, http.StatusUnsupportedMediaType, err.(*misc.HTTPError).Code)
+
+			err = DecompressBody(newCompressedRequest(t, "gzip", data), 1)
+			require.Equal(t, http.StatusBadRequest, err.(*misc.HTTPError).Code)
+		})
+
+		t.Run("should serve compressed request", func(t *testing.T) {
+			var (
+				rec = httptest.NewRecorder()
+				req = newCompressedRequest(t, "gzip", compress(t, "gzip", []byte(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1]}`)))
+			)
+			require.NoError(t, DecompressBody(req, DefaultDecompressedLimit))
+
+			r, err := NewCodec().NewRequest(rec, req)
+			require.NoError(t, err)
+			require.Equal(t, "sum", r.Method())
+			require.Equal(t, `[1]`, string(r.Envelope().Params))
+		})
+	})
+}
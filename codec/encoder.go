@@ -3,11 +3,15 @@ package codec
 import (
 	"io"
 	"net/http"
+	"strconv"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
 )
 
 type (
 	// Encoder interface contains the encoder for http response.
-	// Eg. gzip, flate compressions.
+	// Eg. gzip, flate compressions. Returned writer may buffer data, it
+	// must be closed with CloseEncoded when the response is written.
 	Encoder interface {
 		Encode(w http.ResponseWriter) io.Writer
 	}
@@ -23,6 +27,12 @@ type (
 	encResponseWriter struct {
 		http.ResponseWriter
 		enc Encoder
+		w   io.Writer // created with the first write
+	}
+
+	// nopCloser is io.WriteCloser which doesn't close the writer.
+	nopCloser struct {
+		io.Writer
 	}
 
 	encoder         int
@@ -38,10 +48,56 @@ var (
 
 func (encoder) Encode(w http.ResponseWriter) io.Writer { return w }
 
+func (nopCloser) Close() error { return nil }
+
 func (encoderSelector) Select(_ *http.Request) Encoder { return DefaultEncoder }
 
+// Write writes data with Encoder, it's created with the first write.
 func (w *encResponseWriter) Write(data []byte) (int, error) {
-	return w.enc.Encode(w.ResponseWriter).Write(data)
+	if w.w == nil {
+		w.w = w.enc.Encode(w.ResponseWriter)
+	}
+	return w.w.Write(data)
+}
+
+// Flush implements http.Flusher.
+func (w *encResponseWriter) Flush() {
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	} else if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response written with Encoder.
+func (w *encResponseWriter) Close() error {
+	return CloseEncoded(w.w)
+}
+
+// WriteEncoded writes the whole response data with Encoder and finishes it,
+// Content-Length is set when the response isn't transformed by Encoder.
+func WriteEncoded(w http.ResponseWriter, enc Encoder, data []byte) error {
+	switch enc.(type) {
+	case encoder, identityEncoder:
+		w.Header().Set(misc.HeaderContentLength, strconv.Itoa(len(data)))
+	}
+
+	out := enc.Encode(w)
+	_, err := out.Write(data)
+	if cerr := CloseEncoded(out); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// CloseEncoded closes writer returned by Encoder when it's io.Closer, it
+// must be called once the response is written, so compressed stream is
+// finished.
+func CloseEncoded(w io.Writer) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 //NewEncodedResponse returns http.ResponseWriter, that writes with Encoder
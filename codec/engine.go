@@ -0,0 +1,80 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type (
+	// JSONEngine encodes and decodes JSON, it's used by codec for envelopes,
+	// params and responses. Implementations must follow encoding/json
+	// semantics: struct tags, json.RawMessage, json.Number, json.Marshaler
+	// and json.Unmarshaler must be respected.
+	JSONEngine interface {
+		// Marshal returns JSON encoding of v.
+		Marshal(v interface{}) ([]byte, error)
+		// Unmarshal decodes JSON data into v.
+		Unmarshal(data []byte, v interface{}) error
+		// NewDecoder returns a decoder that reads from r.
+		NewDecoder(r io.Reader) JSONDecoder
+		// NewEncoder returns an encoder that writes to w.
+		NewEncoder(w io.Writer) JSONEncoder
+		// IsDecodeError reports whether err returned by Unmarshal or
+		// decoder means data is malformed or doesn't match the value it's
+		// decoded into, as opposed to other failures.
+		IsDecodeError(err error) bool
+	}
+
+	// JSONDecoder reads and decodes JSON values from an input stream.
+	JSONDecoder interface {
+		Decode(v interface{}) error
+	}
+
+	// JSONEncoder writes JSON values to an output stream, each value is
+	// followed by a newline.
+	JSONEncoder interface {
+		Encode(v interface{}) error
+	}
+
+	// stdEngine is JSONEngine backed by encoding/json.
+	stdEngine struct{}
+)
+
+// StdJSON is JSONEngine backed by encoding/json, it's used by default.
+var StdJSON JSONEngine = stdEngine{}
+
+// WithJSONEngine makes codec use e instead of encoding/json.
+func WithJSONEngine(e JSONEngine) Option {
+	return func(c *codec) {
+		c.engine = e
+	}
+}
+
+// Marshal implements JSONEngine.
+func (stdEngine) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements JSONEngine.
+func (stdEngine) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// NewDecoder implements JSONEngine.
+func (stdEngine) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
+
+// NewEncoder implements JSONEngine.
+func (stdEngine) NewEncoder(w io.Writer) JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+// IsDecodeError implements JSONEngine.
+func (stdEngine) IsDecodeError(err error) bool {
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package codec
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testEngine counts calls of encoding/json and rejects data containing
+// banned string.
+type testEngine struct {
+	banned string
+	calls  map[string]int
+}
+
+var errBanned = errors.New("banned")
+
+func newTestEngine(banned string) *testEngine {
+	return &testEngine{banned: banned, calls: make(map[string]int)}
+}
+
+func (e *testEngine) Marshal(v interface{}) ([]byte, error) {
+	e.calls["Marshal"]++
+	return StdJSON.Marshal(v)
+}
+
+func (e *testEngine) Unmarshal(data []byte, v interface{}) error {
+	e.calls["Unmarshal"]++
+	if e.banned != "" && strings.Contains(string(data), e.banned) {
+		return errBanned
+	}
+	return StdJSON.Unmarshal(data, v)
+}
+
+func (e *testEngine) NewDecoder(r io.Reader) JSONDecoder {
+	e.calls["NewDecoder"]++
+	return StdJSON.NewDecoder(r)
+}
+
+func (e *testEngine) NewEncoder(w io.Writer) JSONEncoder {
+	e.calls["NewEncoder"]++
+	return StdJSON.NewEncoder(w)
+}
+
+func (e *testEngine) IsDecodeError(err error) bool {
+	return err == errBanned || StdJSON.IsDecodeError(err)
+}
+
+func TestJSONEngineSuite(t *testing.T) {
+	t.Run("JSON engine test suite", func(t *testing.T) {
+		t.Run("should serve HTTP request with engine", func(t *testing.T) {
+			var (
+				engine = newTestEngine("")
+				rec    = httptest.NewRecorder()
+			)
+
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1, 2]}`))
+			require.NoError(t, err)
+
+			r, err := NewCodec(WithJSONEngine(engine)).NewRequest(rec, req)
+			require.NoError(t, err)
+
+			var args []int
+			require.NoError(t, r.ReadRequest(&args))
+			require.Equal(t, []int{1, 2}, args)
+
+			r.WriteResponse(3)
+			require.Equal(t, "3", readResult(t, rec.Body.Bytes()))
+			require.Equal(t, map[string]int{"NewDecoder": 1, "Unmarshal": 1, "NewEncoder": 1}, engine.calls)
+		})
+
+		t.Run("should serve messages with engine", func(t *testing.T) {
+			var (
+				engine = newTestEngine("")
+				cdc    = NewCodec(WithJSONEngine(engine))
+				sent   []byte
+			)
+
+			reqs, err := cdc.NewMessage([]byte(`[{"jsonrpc": "2.0", "id": 1, "method": "sum"}]`), func(msg []byte) {
+				sent = msg
+			})
+			require.NoError(t, err)
+			require.Len(t, reqs, 1)
+
+			reqs[0].WriteResponse(1)
+			require.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":1}]`, string(sent))
+			require.Equal(t, map[string]int{"Unmarshal": 2, "Marshal": 1}, engine.calls)
+
+			_, err = cdc.NewNotification("event", nil)
+			require.NoError(t, err)
+			require.Equal(t, 2, engine.calls["Marshal"])
+		})
+
+		t.Run("should report engine errors", func(t *testing.T) {
+			var (
+				cdc  = NewCodec(WithJSONEngine(newTestEngine("deep")))
+				sent []byte
+			)
+
+			reqs, err := cdc.NewMessage([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": {"deep": 1}}`), func(msg []byte) {
+				sent = msg
+			})
+			require.Equal(t, errBanned, err.(*Error).Internal)
+			require.Nil(t, reqs)
+			require.Contains(t, string(sent), `"code":-32700`)
+
+			req, err := http.NewRequest(http.MethodGet, "/?method=sum&params=[%22deep%22]", nil)
+			require.NoError(t, err)
+
+			_, err = NewCodec(WithGET(GETConfig{}), WithJSONEngine(newTestEngine("deep"))).NewRequest(httptest.NewRecorder(), req)
+			require.Error(t, err)
+		})
+
+		t.Run("should classify engine errors", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": ["deep"]}`))
+			require.NoError(t, err)
+
+			r, err := NewCodec(WithJSONEngine(newTestEngine("deep"))).NewRequest(rec, req)
+			require.NoError(t, err)
+
+			err = r.ReadRequest(new([]string))
+			require.True(t, r.HandleError(err))
+			require.Contains(t, rec.Body.String(), `"error":{"code":-32602,"message":"cannot unmarshal request"`)
+		})
+
+		t.Run("should use engine in every codec", func(t *testing.T) {
+			for name, newCodec := range map[string]func(engine JSONEngine) Interface{
+				"custom": func(engine JSONEngine) Interface {
+					return NewCustom(DefaultEncoderSelector, WithJSONEngine(engine))
+				},
+				"version 1": func(engine JSONEngine) Interface {
+					return NewVersion1(DefaultEncoderSelector, WithJSONEngine(engine))
+				},
+				"multipart": func(engine JSONEngine) Interface {
+					return NewMultipart(DefaultEncoderSelector, 0, WithJSONEngine(engine))
+				},
+			} {
+				engine := newTestEngine("")
+				_, err := newCodec(engine).NewMessage([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "sum"}`), func([]byte) {})
+				require.NoError(t, err, name)
+				require.NotZero(t, engine.calls["Unmarshal"], name)
+			}
+
+			engine := newTestEngine("")
+			req := newMultipartRequest(t, `{"jsonrpc": "2.0", "id": 1, "method": "sum"}`, nil)
+			_, err := NewMultipart(DefaultEncoderSelector, 0, WithJSONEngine(engine)).NewRequest(httptest.NewRecorder(), req)
+			require.NoError(t, err)
+			require.Equal(t, 1, engine.calls["Unmarshal"])
+		})
+	})
+}
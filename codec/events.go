@@ -0,0 +1,112 @@
+package codec
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
+)
+
+type (
+	// StreamRequest is implemented by requests that can send notifications
+	// ahead of the response, e.g. as Server-Sent Events.
+	StreamRequest interface {
+		Request
+		// Streaming reports whether client asked for streamed response.
+		Streaming() bool
+		// WriteNotification sends notification before the response.
+		WriteNotification(method string, params interface{}) error
+	}
+
+	// eventStream writes messages as Server-Sent Events, the response is
+	// the last event of the stream.
+	eventStream struct {
+		mu      sync.Mutex
+		w       http.ResponseWriter
+		started bool
+		closed  bool
+	}
+)
+
+// ErrStreamClosed returned when notification is sent after the response.
+var ErrStreamClosed = errors.New("rpc: response is already written")
+
+// acceptsEvents reports whether client accepts event stream.
+func acceptsEvents(r *http.Request) bool {
+	for _, accept := range r.Header[misc.HeaderAccept] {
+		for _, part := range strings.Split(accept, ",") {
+			if mt, _, err := mime.ParseMediaType(part); err == nil && mt == misc.MIMETextEventStream {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Streaming reports whether client asked for event stream.
+func (c *request) Streaming() bool {
+	return c.events != nil
+}
+
+// WriteNotification sends notification as event, it fails when client
+// didn't ask for event stream.
+func (c *request) WriteNotification(method string, params interface{}) error {
+	if c.events == nil {
+		return ErrStreamClosed
+	}
+
+	data, err := c.engine.Marshal(&outgoingRequest{
+		Version: Version,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	return c.events.write(data, false)
+}
+
+// writeEvent writes response as the last event of the stream.
+func (c *request) writeEvent(res *serverResponse) {
+	data, err := c.engine.Marshal(c.response(res))
+	if err != nil {
+		data = encodeError(c.engine, err)
+	}
+	_ = c.events.write(data, true)
+}
+
+// write sends message as event and flushes it, headers are written with
+// the first event.
+func (s *eventStream) write(data []byte, last bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStreamClosed
+	}
+	s.closed = last
+
+	if !s.started {
+		s.started = true
+		header := s.w.Header()
+		header.Set(misc.HeaderContentType, misc.MIMETextEventStream)
+		header.Set(misc.HeaderCacheControl, "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	buf := make([]byte, 0, len(data)+8)
+	buf = append(buf, "data: "...)
+	buf = append(buf, data...)
+	buf = append(buf, "\n\n"...)
+	if _, err := s.w.Write(buf); err != nil {
+		return err
+	}
+
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package codec
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
+)
+
+type (
+	// GETConfig holds settings of HTTP GET invocation.
+	GETConfig struct {
+		// Prefix is the path RPC is served at, e.g. "/rpc". When it's set,
+		// method can be passed with path as in "/rpc/getblockcount",
+		// otherwise only "method" query parameter is used.
+		Prefix string
+
+		// MaxAge tells caches how long successful responses stay fresh.
+		MaxAge time.Duration
+	}
+
+	// getRequest holds caching state of request that came with HTTP GET.
+	getRequest struct {
+		cfg         *GETConfig
+		ifNoneMatch string
+	}
+)
+
+// WithGET allows invoking methods with HTTP GET, as in
+// "/rpc?method=getblockcount&params=[]&id=1". Request is always answered,
+// even without id, and the response is sent with ETag and Cache-Control
+// headers. Server must serve such requests only by safe methods, see
+// Envelope.Safe.
+func WithGET(cfg GETConfig) Option {
+	return func(c *codec) {
+		c.get = &cfg
+	}
+}
+
+// newGETRequest returns a new Request decoded from URL of GET request.
+func (c *codec) newGETRequest(w http.ResponseWriter, r *http.Request, encoder Encoder) (Request, error) {
+	var (
+		cfg   = c.get
+		query = r.URL.Query()
+		req   = &serverRequest{Version: Version, Method: query.Get("method")}
+	)
+
+	if cfg.Prefix != "" && strings.HasPrefix(r.URL.Path, cfg.Prefix+"/") {
+		req.Method = strings.TrimPrefix(r.URL.Path, cfg.Prefix+"/")
+	}
+
+	if req.Method == "" {
+		return nil, &Error{
+			Code:    ErrInvalidRequest,
+			Message: "rpc: method is required",
+		}
+	}
+
+	if id := query.Get("id"); id != "" {
+		req.ID = new(json.Number)
+		if err := c.engine.Unmarshal([]byte(id), req.ID); err != nil {
+			return nil, &Error{
+				Code:    ErrInvalidRequest,
+				Message: "rpc: id must be a number",
+			}
+		}
+	}
+
+	if params := query.Get("params"); params != "" {
+		req.Params = json.RawMessage(params)
+		if c.engine.Unmarshal(req.Params, new(json.RawMessage)) != nil {
+			return nil, &Error{
+				Code:    ErrParse,
+				Message: "rpc: params must be valid JSON",
+			}
+		}
+	}
+
+	env := newEnvelope(req)
+	env.safe = true
+
+	return &request{
+		writer:   w,
+		engine:   c.engine,
+		request:  req,
+		envelope: env,
+		encoder:  encoder,
+		get: &getRequest{
+			cfg:         cfg,
+			ifNoneMatch: r.Header.Get(misc.HeaderIfNoneMatch),
+		},
+	}, nil
+}
+
+// writeCacheable writes response to GET request along with caching headers,
+// errors aren't cached.
+func (c *request) writeCacheable(res *serverResponse) {
+	data, err := c.engine.Marshal(c.response(res))
+	if err != nil {
+		WriteError(c.writer, err)
+		return
+	}
+
+	header := c.writer.Header()
+	header.Set(misc.HeaderContentType, misc.MIMEApplicationJSONCharsetUTF8)
+
+	if res.Error != nil {
+		header.Set(misc.HeaderCacheControl, "no-store")
+	} else {
+		sum := sha1.Sum(data)
+		etag := `W/"` + hex.EncodeToString(sum[:]) + `"`
+
+		header.Set(misc.HeaderETag, etag)
+		header.Set(misc.HeaderCacheControl, "public, max-age="+
+			strconv.FormatInt(int64(c.get.cfg.MaxAge/time.Second), 10))
+
+		if matchETag(c.get.ifNoneMatch, etag) {
+			c.writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	_ = WriteEncoded(c.writer, c.encoder, append(data, '\n'))
+}
+
+// matchETag reports whether If-None-Match header matches the tag, using weak
+// comparison.
+func matchETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
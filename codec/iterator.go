@@ -0,0 +1,181 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
+)
+
+type (
+	// Iterator yields items of result that is encoded as JSON array item by
+	// item, so the whole result is never held in memory. Method can set its
+	// reply to Iterator or to receive channel to stream the result. When
+	// Iterator implements io.Closer, it's closed once the response is
+	// written, otherwise items left after the response are read and
+	// dropped, so producer sending them to the channel isn't blocked.
+	Iterator interface {
+		// Next returns the next item, io.EOF is returned when there are no
+		// more items.
+		Next() (interface{}, error)
+	}
+
+	// chanIterator yields items received from channel until it's closed.
+	chanIterator struct {
+		ch reflect.Value
+	}
+)
+
+const (
+	// streamBufferSize is the size of buffer streamed result is written with.
+	streamBufferSize = 32 << 10
+	// streamFlushSize is the amount of buffered data that's flushed to client.
+	streamFlushSize = 16 << 10
+)
+
+// newIterator returns Iterator of streamed reply or nil when reply isn't
+// streamed, nil channel is treated as empty one.
+func newIterator(reply interface{}) Iterator {
+	v := reflect.ValueOf(reply)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		if it, ok := v.Interface().(Iterator); ok {
+			return it
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case !v.IsValid():
+		return nil
+	case v.Kind() == reflect.Interface && !v.IsNil():
+		it, _ := v.Interface().(Iterator)
+		return it
+	case v.Kind() == reflect.Chan && v.Type().ChanDir()&reflect.RecvDir != 0:
+		return &chanIterator{ch: v}
+	}
+	return nil
+}
+
+// Next receives the next item from channel.
+func (it *chanIterator) Next() (interface{}, error) {
+	if it.ch.IsNil() {
+		return nil, io.EOF
+	}
+	item, ok := it.ch.Recv()
+	if !ok {
+		return nil, io.EOF
+	}
+	return item.Interface(), nil
+}
+
+// writeStreamed writes response with streamed result. Only plain HTTP
+// responses are streamed, other ones are written once the whole result is
+// encoded. Once streaming has started, errors can't be reported anymore, so
+// the response is cut short and client fails to decode it.
+func (c *request) writeStreamed(res *serverResponse, it Iterator) {
+	defer discardIterator(it)
+
+	if c.writer == nil || c.get != nil || c.events != nil {
+		buf := new(bytes.Buffer)
+		if err := writeArray(buf, it, c.engine, nil); err != nil {
+			c.WriteError(http.StatusInternalServerError, &Error{
+				Code:     ErrInternal,
+				Message:  err.Error(),
+				Internal: err,
+			})
+			return
+		}
+		res.Result = json.RawMessage(buf.Bytes())
+		c.writeServerResponse(res)
+		return
+	}
+
+	// ID is null for notifications and they don't have a response.
+	if c.request.ID == nil {
+		return
+	}
+
+	// Result is cut out of encoded response, so the envelope is encoded the
+	// same way it's done for buffered responses.
+	res.Result = json.RawMessage(`[]`)
+	head, err := c.engine.Marshal(c.response(res))
+	if err != nil {
+		WriteError(c.writer, err)
+		return
+	}
+	head = head[:len(head)-len(`[]}`)]
+
+	header := c.writer.Header()
+	header.Set(misc.HeaderXContentTypeOptions, "nosniff")
+	header.Set(misc.HeaderContentType, misc.MIMEApplicationJSONCharsetUTF8)
+
+	w := c.encoder.Encode(c.writer)
+	defer func() { _ = CloseEncoded(w) }()
+
+	var flush func()
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
+	}
+
+	if _, err = w.Write(head); err != nil {
+		return
+	} else if err = writeArray(w, it, c.engine, flush); err != nil {
+		return
+	}
+	_, _ = w.Write([]byte("}\n"))
+}
+
+// discardIterator closes iterator or reads its remaining items, so they
+// aren't left unconsumed when the response is written without them, e.g.
+// for notifications.
+func discardIterator(it Iterator) {
+	if cl, ok := it.(io.Closer); ok {
+		_ = cl.Close()
+		return
+	}
+	for {
+		if _, err := it.Next(); err != nil {
+			return
+		}
+	}
+}
+
+// writeArray encodes items of iterator as JSON array, flush is called every
+// time buffered data is written.
+func writeArray(w io.Writer, it Iterator, engine JSONEngine, flush func()) error {
+	bw := bufio.NewWriterSize(w, streamBufferSize)
+	_ = bw.WriteByte('[')
+
+	for i := 0; ; i++ {
+		item, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		data, err := engine.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			_ = bw.WriteByte(',')
+		}
+		_, _ = bw.Write(data)
+
+		if flush != nil && bw.Buffered() >= streamFlushSize {
+			if err = bw.Flush(); err != nil {
+				return err
+			}
+			flush()
+		}
+	}
+
+	_ = bw.WriteByte(']')
+	return bw.Flush()
+}
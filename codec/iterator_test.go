@@ -0,0 +1,161 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// closeIterator yields nothing and records whether it's closed.
+type closeIterator struct {
+	closed bool
+}
+
+func (it *closeIterator) Next() (interface{}, error) { return nil, errors.New("not expected") }
+
+func (it *closeIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// countIterator yields numbers up to n and fails with err when it's set.
+type countIterator struct {
+	i, n int
+	err  error
+}
+
+func (it *countIterator) Next() (interface{}, error) {
+	if it.i == it.n {
+		if it.err != nil {
+			return nil, it.err
+		}
+		return nil, io.EOF
+	}
+	it.i++
+	return it.i, nil
+}
+
+func readResult(t *testing.T, data []byte) string {
+	res := new(testResponse)
+	require.NoError(t, json.Unmarshal(data, res))
+	require.Nil(t, res.Error)
+	return string(res.Result)
+}
+
+func TestIteratorSuite(t *testing.T) {
+	t.Run("Iterator test suite", func(t *testing.T) {
+		newRequest := func(t *testing.T, rec http.ResponseWriter) Request {
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "list"}`))
+			require.NoError(t, err)
+
+			r, err := NewCodec().NewRequest(rec, req)
+			require.NoError(t, err)
+			return r
+		}
+
+		t.Run("should stream channel result", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			ch := make(chan string, 3)
+			ch <- "a"
+			ch <- "b"
+			close(ch)
+
+			var reply <-chan string = ch
+			newRequest(t, rec).WriteResponse(&reply)
+			require.Equal(t, `["a","b"]`, readResult(t, rec.Body.Bytes()))
+		})
+
+		t.Run("should stream iterator result and flush", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			var reply Iterator = &countIterator{n: 10000}
+			newRequest(t, rec).WriteResponse(&reply)
+			require.True(t, rec.Flushed)
+
+			result := readResult(t, rec.Body.Bytes())
+			require.True(t, strings.HasPrefix(result, `[1,2,3,`))
+			require.True(t, strings.HasSuffix(result, `,9999,10000]`))
+		})
+
+		t.Run("should stream nil channel as empty array", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			var reply <-chan int
+			newRequest(t, rec).WriteResponse(&reply)
+			require.Equal(t, `[]`, readResult(t, rec.Body.Bytes()))
+		})
+
+		t.Run("should cut response on error", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			newRequest(t, rec).WriteResponse(&countIterator{n: 2, err: errors.New("fail")})
+			require.True(t, strings.HasSuffix(rec.Body.String(), `"result":`))
+			require.False(t, json.Valid(rec.Body.Bytes()))
+		})
+
+		t.Run("should release iterator of notification", func(t *testing.T) {
+			newNotification := func(rec http.ResponseWriter) Request {
+				req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "2.0", "method": "list"}`))
+				require.NoError(t, err)
+
+				r, err := NewCodec().NewRequest(rec, req)
+				require.NoError(t, err)
+				return r
+			}
+
+			var (
+				ch   = make(chan int)
+				done = make(chan struct{})
+			)
+			go func() {
+				defer close(done)
+				for i := 0; i < 3; i++ {
+					ch <- i
+				}
+				close(ch)
+			}()
+
+			rec := httptest.NewRecorder()
+			var reply <-chan int = ch
+			newNotification(rec).WriteResponse(&reply)
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "producer is blocked")
+			}
+			require.Empty(t, rec.Body.String())
+
+			it := new(closeIterator)
+			newNotification(httptest.NewRecorder()).WriteResponse(it)
+			require.True(t, it.closed)
+		})
+
+		t.Run("should encode whole result for messages", func(t *testing.T) {
+			var sent []byte
+			reqs, err := NewCodec().NewMessage([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "list"}`), func(msg []byte) {
+				sent = msg
+			})
+			require.NoError(t, err)
+			require.Len(t, reqs, 1)
+
+			reqs[0].WriteResponse(&countIterator{n: 3})
+			require.Equal(t, `[1,2,3]`, readResult(t, sent))
+
+			reqs, err = NewCodec().NewMessage([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "list"}`), func(msg []byte) {
+				sent = msg
+			})
+			require.NoError(t, err)
+
+			reqs[0].WriteResponse(&countIterator{n: 3, err: errors.New("fail")})
+			require.Contains(t, string(sent), `"error":{"code":-32603,"message":"fail"}`)
+		})
+	})
+}
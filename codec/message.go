@@ -0,0 +1,285 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+type (
+	// MessageCodec decodes requests from raw messages, independently of
+	// transport they were received with.
+	MessageCodec interface {
+		// NewMessage decodes single request or batch of requests from msg,
+		// encoded responses are passed to send. Responses to batch requests
+		// are sent at once, when all of them are written. If msg can't be
+		// decoded, error response is sent and error is returned.
+		NewMessage(msg []byte, send func([]byte)) ([]Request, error)
+
+		// NewNotification encodes notification sent by the server.
+		NewNotification(method string, params interface{}) ([]byte, error)
+
+		// NewCall encodes request sent by the server, its response is
+		// matched by id.
+		NewCall(id uint64, method string, params interface{}) ([]byte, error)
+
+		// NewResponse decodes response to the request sent by the server,
+		// false is returned when msg is not a response.
+		NewResponse(msg []byte) (Response, bool)
+	}
+
+	// BinaryCodec is implemented by message codecs encoding messages in
+	// binary format, e.g. MessagePack. Transports telling text messages from
+	// binary ones use it to choose the message type.
+	BinaryCodec interface {
+		// Binary returns true when encoded messages aren't UTF-8 text.
+		Binary() bool
+	}
+
+	// Response is a response to the request sent by the server.
+	Response interface {
+		// ID of the request.
+		ID() uint64
+		// Err returns an error sent by the client.
+		Err() error
+		// ReadResult decodes the result into reply.
+		ReadResult(reply interface{}) error
+	}
+
+	// outgoingRequest represents a request sent by the server.
+	outgoingRequest struct {
+		// JSON-RPC protocol.
+		Version string `json:"jsonrpc"`
+
+		// The request id, omitted for notifications.
+		ID *uint64 `json:"id,omitempty"`
+
+		// A String containing the name of the method to be invoked.
+		Method string `json:"method"`
+
+		// A Structured value to pass as arguments to the method.
+		Params interface{} `json:"params,omitempty"`
+	}
+
+	// clientResponse represents a response received by the server.
+	clientResponse struct {
+		// The request id.
+		ID json.RawMessage `json:"id"`
+
+		// Responses don't have a method, it's used to tell them from
+		// requests.
+		Method string `json:"method"`
+
+		// The result of successful call.
+		Result json.RawMessage `json:"result"`
+
+		// An Error object if there was an error.
+		Error *Error `json:"error"`
+	}
+
+	// response is decoded response to the request sent by the server.
+	response struct {
+		id     uint64
+		res    *clientResponse
+		engine JSONEngine
+	}
+
+	// batch collects responses to requests of a batch.
+	batch struct {
+		mu      sync.Mutex
+		pending int
+		items   [][]byte
+		send    func([]byte)
+	}
+)
+
+// NewMessage returns requests decoded from msg.
+func (c *codec) NewMessage(msg []byte, send func([]byte)) ([]Request, error) {
+	if !isBatch(msg) {
+		req, err := c.newMessageRequest(msg, send)
+		if err != nil {
+			send(encodeError(c.engine, err))
+			return nil, err
+		}
+		return []Request{req}, nil
+	}
+
+	var items []json.RawMessage
+	if err := c.engine.Unmarshal(msg, &items); err != nil {
+		err = &Error{
+			Code:     ErrParse,
+			Message:  err.Error(),
+			Internal: err,
+		}
+		send(encodeError(c.engine, err))
+		return nil, err
+	} else if len(items) == 0 {
+		err = &Error{
+			Code:    ErrInvalidRequest,
+			Message: "empty batch",
+		}
+		send(encodeError(c.engine, err))
+		return nil, err
+	}
+
+	var (
+		b    = &batch{send: send}
+		reqs = make([]Request, 0, len(items))
+		errs = make([][]byte, 0)
+	)
+
+	for _, item := range items {
+		req, err := c.newMessageRequest(item, b.write)
+		if err != nil {
+			errs = append(errs, encodeError(c.engine, err))
+			continue
+		}
+		// Notifications don't have a response.
+		if req.request.ID != nil {
+			b.pending++
+		}
+		reqs = append(reqs, req)
+	}
+
+	b.pending += len(errs)
+	for _, res := range errs {
+		b.write(res)
+	}
+
+	return reqs, nil
+}
+
+// NewNotification returns encoded notification.
+func (c *codec) NewNotification(method string, params interface{}) ([]byte, error) {
+	return c.engine.Marshal(&outgoingRequest{
+		Version: Version,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// NewCall returns encoded request.
+func (c *codec) NewCall(id uint64, method string, params interface{}) ([]byte, error) {
+	return c.engine.Marshal(&outgoingRequest{
+		Version: Version,
+		ID:      &id,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// NewResponse returns response decoded from msg.
+func (c *codec) NewResponse(msg []byte) (Response, bool) {
+	if isBatch(msg) {
+		return nil, false
+	}
+
+	res := new(clientResponse)
+	if err := c.engine.Unmarshal(msg, res); err != nil ||
+		res.Method != "" || (res.Result == nil && res.Error == nil) {
+		return nil, false
+	}
+
+	// The client may send id back as a string.
+	var id uint64
+	if err := c.engine.Unmarshal(res.ID, &id); err != nil {
+		var str string
+		if err = c.engine.Unmarshal(res.ID, &str); err != nil {
+			return nil, false
+		} else if id, err = strconv.ParseUint(str, 10, 64); err != nil {
+			return nil, false
+		}
+	}
+	return &response{id: id, res: res, engine: c.engine}, true
+}
+
+// ID returns the request id.
+func (r *response) ID() uint64 {
+	return r.id
+}
+
+// Err returns an error sent by the client.
+func (r *response) Err() error {
+	if r.res.Error != nil {
+		return r.res.Error
+	}
+	return nil
+}
+
+// ReadResult decodes the result into reply.
+func (r *response) ReadResult(reply interface{}) error {
+	return r.engine.Unmarshal(r.res.Result, reply)
+}
+
+// isBatch reports whether msg holds JSON array.
+func isBatch(msg []byte) bool {
+	msg = bytes.TrimLeft(msg, " \t\r\n")
+	return len(msg) > 0 && msg[0] == '['
+}
+
+// newMessageRequest returns request decoded from msg.
+func (c *codec) newMessageRequest(msg []byte, send func([]byte)) (*request, error) {
+	req := getServerRequest()
+	if err := c.engine.Unmarshal(msg, req); err != nil {
+		return nil, newParseError(req, err)
+	}
+
+	v1, err := c.checkVersion(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := c.newRequest(req, v1)
+	res.send = send
+	return res, nil
+}
+
+// encodeError returns encoded error response for request which id
+// can't be detected.
+func encodeError(engine JSONEngine, err error) []byte {
+	// Error fields are always serializable, error can be omitted.
+	data, _ := engine.Marshal(&serverResponse{
+		Version: Version,
+		Error:   newError(err),
+	})
+	return data
+}
+
+// sendServerResponse encodes the response and passes it to send.
+func (c *request) sendServerResponse(res *serverResponse) {
+	// ID is null for notifications and they don't have a response.
+	if c.request.ID == nil {
+		return
+	}
+
+	data, err := c.engine.Marshal(c.response(res))
+	if err != nil {
+		data, _ = c.engine.Marshal(&serverResponse{
+			Version: Version,
+			ID:      c.request.ID,
+			Error: &Error{
+				Code:    ErrInternal,
+				Message: err.Error(),
+			},
+		})
+	}
+	c.send(data)
+}
+
+// write adds response to the batch and sends the batch when all of its
+// responses are collected.
+func (b *batch) write(res []byte) {
+	b.mu.Lock()
+	b.items = append(b.items, res)
+	b.pending--
+	if b.pending != 0 {
+		b.mu.Unlock()
+		return
+	}
+	msg := append([]byte{'['}, bytes.Join(b.items, []byte{','})...)
+	msg = append(msg, ']')
+	b.mu.Unlock()
+
+	b.send(msg)
+}
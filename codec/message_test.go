@@ -0,0 +1,162 @@
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testResponse struct {
+	ID     *json.Number    `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func TestMessageSuite(t *testing.T) {
+	t.Run("Message codec test suite", func(t *testing.T) {
+		t.Run("should decode single request", func(t *testing.T) {
+			var (
+				sent  [][]byte
+				codec = NewCodec()
+			)
+
+			reqs, err := codec.NewMessage([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "someMethod", "params": "params"}`),
+				func(msg []byte) { sent = append(sent, msg) })
+			require.NoError(t, err)
+			require.Len(t, reqs, 1)
+			require.Equal(t, "someMethod", reqs[0].Method())
+
+			var args string
+			require.NoError(t, reqs[0].ReadRequest(&args))
+			require.Equal(t, "params", args)
+
+			reqs[0].WriteResponse(args)
+			require.Len(t, sent, 1)
+
+			res := new(testResponse)
+			require.NoError(t, json.Unmarshal(sent[0], res))
+			require.Equal(t, "1", res.ID.String())
+			require.Equal(t, `"params"`, string(res.Result))
+		})
+
+		t.Run("should not respond to notifications", func(t *testing.T) {
+			var (
+				sent  [][]byte
+				codec = NewCodec()
+			)
+
+			reqs, err := codec.NewMessage([]byte(`{"jsonrpc": "2.0", "method": "someMethod"}`),
+				func(msg []byte) { sent = append(sent, msg) })
+			require.NoError(t, err)
+			require.Len(t, reqs, 1)
+
+			reqs[0].WriteResponse(nil)
+			require.Empty(t, sent)
+		})
+
+		t.Run("should respond to batch at once", func(t *testing.T) {
+			var (
+				sent  [][]byte
+				codec = NewCodec()
+			)
+
+			reqs, err := codec.NewMessage([]byte(`[
+				{"jsonrpc": "2.0", "id": 1, "method": "first"},
+				{"jsonrpc": "2.0", "method": "notification"},
+				{"jsonrpc": "1.0", "id": 2, "method": "invalid"},
+				{"jsonrpc": "2.0", "id": 3, "method": "second"}
+			]`), func(msg []byte) { sent = append(sent, msg) })
+			require.NoError(t, err)
+			require.Len(t, reqs, 3)
+
+			for _, req := range reqs {
+				require.Empty(t, sent)
+				req.WriteResponse(req.Method())
+			}
+			require.Len(t, sent, 1)
+
+			var res []testResponse
+			require.NoError(t, json.Unmarshal(sent[0], &res))
+			require.Len(t, res, 3)
+
+			require.Nil(t, res[0].ID)
+			require.Equal(t, ErrInvalidRequest, res[0].Error.Code)
+			require.Equal(t, "1", res[1].ID.String())
+			require.Equal(t, `"first"`, string(res[1].Result))
+			require.Equal(t, "3", res[2].ID.String())
+			require.Equal(t, `"second"`, string(res[2].Result))
+		})
+
+		t.Run("should fail on bad message", func(t *testing.T) {
+			for msg, code := range map[string]int{
+				`invalid`:                     ErrParse,
+				`[invalid`:                    ErrParse,
+				`[]`:                          ErrInvalidRequest,
+				`{"jsonrpc": "1.0", "id": 1}`: ErrInvalidRequest,
+			} {
+				var (
+					sent  [][]byte
+					codec = NewCodec()
+				)
+
+				reqs, err := codec.NewMessage([]byte(msg), func(msg []byte) { sent = append(sent, msg) })
+				require.Error(t, err, msg)
+				require.Empty(t, reqs, msg)
+				require.Len(t, sent, 1, msg)
+
+				res := new(testResponse)
+				require.NoError(t, json.Unmarshal(sent[0], res), msg)
+				require.Nil(t, res.ID, msg)
+				require.Equal(t, code, res.Error.Code, msg)
+			}
+		})
+	})
+}
+
+func TestResponseSuite(t *testing.T) {
+	t.Run("Response codec test suite", func(t *testing.T) {
+		t.Run("should encode call", func(t *testing.T) {
+			msg, err := NewCodec().NewCall(5, "sign", []string{"tx"})
+			require.NoError(t, err)
+			require.JSONEq(t, `{"jsonrpc": "2.0", "id": 5, "method": "sign", "params": ["tx"]}`, string(msg))
+
+			msg, err = NewCodec().NewNotification("event", nil)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"jsonrpc": "2.0", "method": "event"}`, string(msg))
+		})
+
+		t.Run("should decode response", func(t *testing.T) {
+			for msg, id := range map[string]uint64{
+				`{"jsonrpc": "2.0", "id": 1, "result": "ok"}`:   1,
+				`{"jsonrpc": "2.0", "id": "2", "result": "ok"}`: 2,
+			} {
+				res, ok := NewCodec().NewResponse([]byte(msg))
+				require.True(t, ok, msg)
+				require.Equal(t, id, res.ID(), msg)
+				require.NoError(t, res.Err(), msg)
+
+				var reply string
+				require.NoError(t, res.ReadResult(&reply), msg)
+				require.Equal(t, "ok", reply, msg)
+			}
+
+			res, ok := NewCodec().NewResponse([]byte(`{"jsonrpc": "2.0", "id": 3, "error": {"code": 1, "message": "fail"}}`))
+			require.True(t, ok)
+			require.EqualError(t, res.Err(), "fail")
+		})
+
+		t.Run("should not decode requests", func(t *testing.T) {
+			for _, msg := range []string{
+				`invalid`,
+				`[{"jsonrpc": "2.0", "id": 1, "result": "ok"}]`,
+				`{"jsonrpc": "2.0", "id": 1, "method": "someMethod"}`,
+				`{"jsonrpc": "2.0", "id": 1}`,
+				`{"jsonrpc": "2.0", "id": "abc", "result": "ok"}`,
+			} {
+				_, ok := NewCodec().NewResponse([]byte(msg))
+				require.False(t, ok, msg)
+			}
+		})
+	})
+}
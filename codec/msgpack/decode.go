@@ -0,0 +1,75 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// errNotMap returned when message isn't encoded as map.
+var errNotMap = errors.New("message must be a map")
+
+// decodeMessage decodes envelope members of the message, params, result and
+// error are sliced out of data without decoding, so they're decoded only
+// once, right into method arguments.
+func decodeMessage(data []byte) (*message, error) {
+	var (
+		r = bytes.NewReader(data)
+		d = msgpack.NewDecoder(r)
+		m = new(message)
+	)
+
+	n, err := d.DecodeMapLen()
+	if err != nil {
+		return nil, err
+	} else if n < 0 {
+		return nil, errNotMap
+	}
+
+	// raw returns the next value as is, bytes.Reader isn't buffered by
+	// decoder, so its position matches the decoded one.
+	raw := func() ([]byte, error) {
+		start := len(data) - r.Len()
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		return data[start : len(data)-r.Len()], nil
+	}
+
+	for i := 0; i < n; i++ {
+		key, err := d.DecodeString()
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case "jsonrpc":
+			m.Version, err = d.DecodeString()
+		case "id":
+			m.ID, err = d.DecodeInterfaceLoose()
+		case "method":
+			m.Method, err = d.DecodeString()
+		case "params":
+			m.Params, err = raw()
+		case "result":
+			m.Result, err = raw()
+		case "error":
+			if m.Error, err = raw(); err == nil && isNil(m.Error) {
+				m.Error = nil
+			}
+		default:
+			err = d.Skip()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// isNil reports whether encoded value is nil.
+func isNil(data []byte) bool {
+	return len(data) == 1 && data[0] == 0xc0
+}
@@ -0,0 +1,336 @@
+// Package msgpack implements MessagePack codec, it has the same envelope as
+// JSON-RPC 2.0 with members encoded as MessagePack map.
+package msgpack
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+type (
+	// message represents decoded envelope of request or response, params,
+	// result and error are left encoded.
+	message struct {
+		Version string
+		ID      interface{}
+		Method  string
+		Params  []byte
+		Result  []byte
+		Error   []byte
+	}
+
+	// serverResponse represents a response returned by the server.
+	serverResponse struct {
+		Version string       `msgpack:"jsonrpc"`
+		ID      interface{}  `msgpack:"id"`
+		Result  interface{}  `msgpack:"result,omitempty"`
+		Error   *codec.Error `msgpack:"error,omitempty"`
+	}
+
+	// outgoingRequest represents a request sent by the server.
+	outgoingRequest struct {
+		Version string      `msgpack:"jsonrpc"`
+		ID      *uint64     `msgpack:"id,omitempty"`
+		Method  string      `msgpack:"method"`
+		Params  interface{} `msgpack:"params,omitempty"`
+	}
+
+	// msgpackCodec creates a Request to process each request.
+	msgpackCodec struct {
+		encSel codec.EncoderSelector
+	}
+
+	// request decodes a single request and encodes its response.
+	request struct {
+		writer   http.ResponseWriter // nil for requests decoded from messages
+		send     func([]byte)
+		message  *message
+		envelope *codec.Envelope
+		encoder  codec.Encoder
+	}
+
+	// response is a response to the request sent by the server.
+	response struct {
+		id  uint64
+		msg *message
+	}
+)
+
+// NewCodec returns a new MessagePack codec based on passed encoder selector,
+// struct fields are encoded using json tags when there are no msgpack ones.
+func NewCodec(encSel codec.EncoderSelector) codec.Interface {
+	return &msgpackCodec{encSel: encSel}
+}
+
+// Binary implements codec.BinaryCodec, MessagePack messages are binary.
+func (c *msgpackCodec) Binary() bool {
+	return true
+}
+
+// NewRequest returns a Request.
+func (c *msgpackCodec) NewRequest(w http.ResponseWriter, r *http.Request) (codec.Request, error) {
+	defer func() {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+	}()
+
+	if r.Method != http.MethodPost {
+		return nil, &codec.Error{
+			Code:    codec.ErrInvalidRequest,
+			Message: "rpc: POST method required, received " + r.Method,
+		}
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, newParseError(err)
+	}
+
+	req, err := newRequest(data)
+	if err != nil {
+		return nil, err
+	}
+	req.writer, req.encoder = w, c.encSel.Select(r)
+	return req, nil
+}
+
+// NewMessage decodes single request from msg, batches aren't supported.
+func (c *msgpackCodec) NewMessage(msg []byte, send func([]byte)) ([]codec.Request, error) {
+	req, err := newRequest(msg)
+	if err != nil {
+		data, _ := marshal(&serverResponse{Version: codec.Version, Error: toError(err)})
+		send(data)
+		return nil, err
+	}
+	req.send = send
+	return []codec.Request{req}, nil
+}
+
+// NewNotification encodes notification sent by the server.
+func (c *msgpackCodec) NewNotification(method string, params interface{}) ([]byte, error) {
+	return marshal(&outgoingRequest{
+		Version: codec.Version,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// NewCall encodes request sent by the server.
+func (c *msgpackCodec) NewCall(id uint64, method string, params interface{}) ([]byte, error) {
+	return marshal(&outgoingRequest{
+		Version: codec.Version,
+		ID:      &id,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// NewResponse decodes response to the request sent by the server.
+func (c *msgpackCodec) NewResponse(msg []byte) (codec.Response, bool) {
+	m, err := decodeMessage(msg)
+	if err != nil || m.Method != "" || (m.Result == nil && m.Error == nil) {
+		return nil, false
+	}
+
+	var id uint64
+	switch v := m.ID.(type) {
+	case uint64:
+		id = v
+	case int64:
+		if v < 0 {
+			return nil, false
+		}
+		id = uint64(v)
+	default:
+		return nil, false
+	}
+	return &response{id: id, msg: m}, true
+}
+
+// NewResponder returns Responder writing MessagePack responses to the
+// request decoded by other codec.
+func (c *msgpackCodec) NewResponder(w http.ResponseWriter, r *http.Request, env *codec.Envelope) (codec.Responder, error) {
+	m := &message{Version: codec.Version, Method: env.Method}
+	if env.ID != "" {
+		m.ID = env.IDValue()
+	}
+	return &request{
+		writer:   w,
+		message:  m,
+		envelope: env,
+		encoder:  c.encSel.Select(r),
+	}, nil
+}
+
+// newRequest returns request decoded from data.
+func newRequest(data []byte) (*request, error) {
+	m, err := decodeMessage(data)
+	if err != nil {
+		return nil, newParseError(err)
+	} else if m.Version != codec.Version {
+		return nil, &codec.Error{
+			Code:    codec.ErrInvalidRequest,
+			Message: "jsonrpc must be " + codec.Version,
+		}
+	}
+
+	env := &codec.Envelope{
+		Version: m.Version,
+		Method:  m.Method,
+		Params:  m.Params,
+	}
+	if m.ID != nil {
+		env.ID = fmt.Sprint(m.ID)
+	}
+	return &request{message: m, envelope: env, encoder: codec.DefaultEncoder}, nil
+}
+
+// newParseError returns error for request that can't be decoded.
+func newParseError(err error) error {
+	return &codec.Error{
+		Code:     codec.ErrParse,
+		Message:  err.Error(),
+		Internal: err,
+	}
+}
+
+// HandleError writes error response, true is returned when err isn't nil.
+func (c *request) HandleError(err error) bool {
+	switch err := err.(type) {
+	case nil:
+		return false
+	case *misc.HTTPError:
+		c.WriteError(err.Code, err)
+	case *codec.Error:
+		c.WriteError(err.Code, err)
+	default:
+		c.WriteError(http.StatusBadRequest, err)
+	}
+	return true
+}
+
+// Envelope returns the parsed envelope of the current request.
+func (c *request) Envelope() *codec.Envelope {
+	return c.envelope
+}
+
+// Method returns the RPC method for the current request.
+func (c *request) Method() string {
+	return c.envelope.Method
+}
+
+// ReadRequest fills the request object for the RPC method, params can be
+// passed by-position as array or by-name as map.
+func (c *request) ReadRequest(args interface{}) error {
+	if params := c.envelope.Params; params != nil {
+		if err := unmarshal(params, args); err != nil {
+			return &codec.Error{
+				Code:     codec.ErrBadParams,
+				Message:  err.Error(),
+				Internal: err,
+			}
+		}
+	}
+	return nil
+}
+
+// WriteResponse encodes the response and writes it.
+func (c *request) WriteResponse(reply interface{}) {
+	c.writeServerResponse(&serverResponse{
+		Version: codec.Version,
+		ID:      c.message.ID,
+		Result:  reply,
+	})
+}
+
+// WriteError encodes the error and writes it.
+func (c *request) WriteError(status int, err error) {
+	c.writeServerResponse(&serverResponse{
+		Version: codec.Version,
+		ID:      c.message.ID,
+		Error:   toError(err),
+	})
+}
+
+func (c *request) writeServerResponse(res *serverResponse) {
+	// ID is null for notifications and they don't have a response.
+	if res.ID == nil {
+		return
+	}
+
+	data, err := marshal(res)
+	if err != nil {
+		data, _ = marshal(&serverResponse{
+			Version: codec.Version,
+			ID:      res.ID,
+			Error: &codec.Error{
+				Code:    codec.ErrInternal,
+				Message: err.Error(),
+			},
+		})
+	}
+
+	if c.writer == nil {
+		c.send(data)
+		return
+	}
+
+	c.writer.Header().Set(misc.HeaderXContentTypeOptions, "nosniff")
+	c.writer.Header().Set(misc.HeaderContentType, misc.MIMEApplicationMsgpack)
+	_ = codec.WriteEncoded(c.writer, c.encoder, data)
+}
+
+// ID of the request.
+func (r *response) ID() uint64 {
+	return r.id
+}
+
+// Err returns an error sent by the client.
+func (r *response) Err() error {
+	if r.msg.Error == nil {
+		return nil
+	}
+
+	err := new(codec.Error)
+	if e := unmarshal(r.msg.Error, err); e != nil {
+		return e
+	}
+	return err
+}
+
+// ReadResult decodes the result into reply.
+func (r *response) ReadResult(reply interface{}) error {
+	return unmarshal(r.msg.Result, reply)
+}
+
+// toError converts err into codec.Error.
+func toError(err error) *codec.Error {
+	if e, ok := err.(*codec.Error); ok {
+		return e
+	}
+	return &codec.Error{
+		Code:    codec.ErrServer,
+		Message: err.Error(),
+	}
+}
+
+// marshal encodes v using json tags as fallback.
+func marshal(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := msgpack.NewEncoder(buf).UseJSONTag(true).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshal decodes data into v using json tags as fallback.
+func unmarshal(data []byte, v interface{}) error {
+	return msgpack.NewDecoder(bytes.NewReader(data)).UseJSONTag(true).Decode(v)
+}
@@ -0,0 +1,176 @@
+package msgpack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+type (
+	testArgs struct {
+		Name string `json:"name"`
+		Data []byte `json:"data"`
+	}
+
+	testResponse struct {
+		Version string       `msgpack:"jsonrpc"`
+		ID      interface{}  `msgpack:"id"`
+		Result  interface{}  `msgpack:"result"`
+		Error   *codec.Error `msgpack:"error"`
+	}
+)
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := msgpack.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func readResponse(t *testing.T, data []byte) *testResponse {
+	res := new(testResponse)
+	require.NoError(t, unmarshal(data, res))
+	return res
+}
+
+func TestCodecSuite(t *testing.T) {
+	t.Run("MessagePack codec test suite", func(t *testing.T) {
+		t.Run("should decode params by name and position", func(t *testing.T) {
+			for _, params := range []interface{}{
+				map[string]interface{}{"name": "nns", "data": []byte{0, 1}},
+				[]interface{}{"nns", []byte{0, 1}},
+			} {
+				rec := httptest.NewRecorder()
+				req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(mustMarshal(t, map[string]interface{}{
+					"jsonrpc": "2.0",
+					"id":      1,
+					"method":  "deploy",
+					"params":  params,
+					"extra":   []int{1, 2},
+				})))
+				require.NoError(t, err)
+
+				r, err := NewCodec(codec.DefaultEncoderSelector).NewRequest(rec, req)
+				require.NoError(t, err)
+				require.Equal(t, "deploy", r.Method())
+				require.Equal(t, "1", r.Envelope().ID)
+
+				args := new(testArgs)
+				require.NoError(t, r.ReadRequest(args))
+				require.Equal(t, &testArgs{Name: "nns", Data: []byte{0, 1}}, args)
+
+				r.WriteResponse(args)
+				require.Equal(t, misc.MIMEApplicationMsgpack, rec.Header().Get(misc.HeaderContentType))
+
+				res := readResponse(t, rec.Body.Bytes())
+				require.Equal(t, codec.Version, res.Version)
+				require.EqualValues(t, 1, res.ID)
+				require.Nil(t, res.Error)
+				require.Equal(t, map[string]interface{}{"name": "nns", "data": []byte{0, 1}}, res.Result)
+			}
+		})
+
+		t.Run("should compress response", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(mustMarshal(t, map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      "a",
+				"method":  "sum",
+			})))
+			require.NoError(t, err)
+			req.Header.Set(misc.HeaderAcceptEncoding, "gzip")
+
+			r, err := NewCodec(new(codec.CompressionSelector)).NewRequest(rec, req)
+			require.NoError(t, err)
+			r.WriteError(http.StatusOK, &codec.Error{Code: codec.ErrNoMethod, Message: "Method not found"})
+
+			gz, err := gzip.NewReader(rec.Body)
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			_, err = buf.ReadFrom(gz)
+			require.NoError(t, err)
+
+			res := readResponse(t, buf.Bytes())
+			require.Equal(t, "a", res.ID)
+			require.Nil(t, res.Result)
+			require.Equal(t, codec.ErrNoMethod, res.Error.Code)
+		})
+
+		t.Run("should fail on bad request", func(t *testing.T) {
+			for _, body := range [][]byte{
+				[]byte("\xc1"),
+				mustMarshal(t, []int{1}),
+				mustMarshal(t, map[string]interface{}{"id": 1, "method": "sum"}),
+			} {
+				req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+				require.NoError(t, err)
+
+				_, err = NewCodec(codec.DefaultEncoderSelector).NewRequest(httptest.NewRecorder(), req)
+				require.IsType(t, (*codec.Error)(nil), err)
+			}
+		})
+
+		t.Run("should serve messages", func(t *testing.T) {
+			var (
+				cdc  = NewCodec(codec.DefaultEncoderSelector)
+				sent []byte
+			)
+			require.True(t, cdc.(codec.BinaryCodec).Binary())
+
+			reqs, err := cdc.NewMessage(mustMarshal(t, map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      2,
+				"method":  "sum",
+				"params":  []int{1, 2},
+			}), func(msg []byte) { sent = msg })
+			require.NoError(t, err)
+			require.Len(t, reqs, 1)
+
+			var args []int
+			require.NoError(t, reqs[0].ReadRequest(&args))
+			require.Equal(t, []int{1, 2}, args)
+
+			reqs[0].WriteResponse(3)
+			require.EqualValues(t, 3, readResponse(t, sent).Result)
+
+			_, err = cdc.NewMessage([]byte("\xc1"), func(msg []byte) { sent = msg })
+			require.Error(t, err)
+			require.Equal(t, codec.ErrParse, readResponse(t, sent).Error.Code)
+		})
+
+		t.Run("should encode calls and decode responses", func(t *testing.T) {
+			cdc := NewCodec(codec.DefaultEncoderSelector)
+
+			msg, err := cdc.NewCall(5, "sign", []string{"tx"})
+			require.NoError(t, err)
+
+			call := make(map[string]interface{})
+			require.NoError(t, msgpack.Unmarshal(msg, &call))
+			require.EqualValues(t, 5, call["id"])
+			require.Equal(t, "sign", call["method"])
+
+			res, ok := cdc.NewResponse(mustMarshal(t, map[string]interface{}{"jsonrpc": "2.0", "id": 5, "result": "signed"}))
+			require.True(t, ok)
+			require.EqualValues(t, 5, res.ID())
+			require.NoError(t, res.Err())
+
+			var reply string
+			require.NoError(t, res.ReadResult(&reply))
+			require.Equal(t, "signed", reply)
+
+			res, ok = cdc.NewResponse(mustMarshal(t, map[string]interface{}{"jsonrpc": "2.0", "id": 6, "error": map[string]interface{}{"code": 1, "message": "fail"}}))
+			require.True(t, ok)
+			require.Equal(t, &codec.Error{Code: 1, Message: "fail"}, res.Err())
+
+			_, ok = cdc.NewResponse(msg)
+			require.False(t, ok)
+		})
+	})
+}
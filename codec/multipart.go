@@ -0,0 +1,277 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
+)
+
+type (
+	// Blob is binary attachment of multipart request. Params reference it
+	// with the name of the part holding it, e.g. {"contract": "file"}, and
+	// handler reads it as io.Reader.
+	Blob struct {
+		// Name of the part.
+		Name string
+		// Filename sent with the part, if any.
+		Filename string
+		// Size of the attachment in bytes.
+		Size int64
+
+		r io.Reader
+	}
+
+	// multipartCodec decodes requests sent as multipart/form-data, the
+	// envelope is in the "request" part and blobs are in the other ones.
+	multipartCodec struct {
+		*codec
+		maxMemory int64
+	}
+
+	// countingBody counts bytes read from request body, so exceeding the
+	// limit of http.MaxBytesReader can be told from other errors.
+	countingBody struct {
+		io.ReadCloser
+		n int64
+	}
+
+	// blobs holds parts of multipart request, they're released when the
+	// request is done.
+	blobs struct {
+		mu    sync.Mutex
+		form  *multipart.Form
+		files []multipart.File
+	}
+)
+
+const (
+	// MultipartRequestPart is the name of the part holding request envelope.
+	MultipartRequestPart = "request"
+
+	// DefaultMultipartMemory is the amount of multipart request kept in
+	// memory, the rest of parts is stored in temporary files.
+	DefaultMultipartMemory = 32 << 20
+
+	// DefaultMultipartLimit is the default limit of multipart request body.
+	DefaultMultipartLimit = 256 << 20
+)
+
+var (
+	// ErrNoBlob returned when reading blob that isn't attached to request.
+	ErrNoBlob = errors.New("rpc: blob isn't attached")
+
+	// typeOfBlob is the reflect.Type of Blob.
+	typeOfBlob = reflect.TypeOf(Blob{})
+)
+
+// NewMultipart returns a codec decoding multipart/form-data requests,
+// maxMemory limits the amount of request kept in memory. It handles
+// messages of other transports the same way JSON codec does.
+func NewMultipart(encSel EncoderSelector, maxMemory int64, opts ...Option) Interface {
+	if maxMemory <= 0 {
+		maxMemory = DefaultMultipartMemory
+	}
+	c := &codec{encSel: encSel, engine: StdJSON, maxBody: DefaultMultipartLimit}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return &multipartCodec{
+		codec:     c,
+		maxMemory: maxMemory,
+	}
+}
+
+// WithMultipartLimit sets limit of multipart request body, parts that
+// aren't kept in memory are stored on disk, so it bounds the disk usage.
+// DefaultMultipartLimit is used by default.
+func WithMultipartLimit(size int64) Option {
+	return func(c *codec) {
+		c.maxBody = size
+	}
+}
+
+// NewRequest returns a Request decoded from multipart form.
+func (c *multipartCodec) NewRequest(w http.ResponseWriter, r *http.Request) (Request, error) {
+	if r.Method != http.MethodPost {
+		return nil, &Error{
+			Code:    ErrInvalidRequest,
+			Message: "rpc: POST method required, received " + r.Method,
+		}
+	}
+
+	body := &countingBody{ReadCloser: r.Body}
+	r.Body = http.MaxBytesReader(w, body, c.maxBody)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, &Error{
+			Code:     ErrInvalidRequest,
+			Message:  err.Error(),
+			Internal: err,
+		}
+	}
+
+	form, err := mr.ReadForm(c.maxMemory)
+	if err != nil && body.n > c.maxBody {
+		return nil, misc.NewHTTPError(http.StatusRequestEntityTooLarge, "rpc: request body is too large")
+	} else if err != nil {
+		return nil, &Error{
+			Code:     ErrParse,
+			Message:  err.Error(),
+			Internal: err,
+		}
+	}
+
+	// Parts are released when the request is closed, or right away when
+	// it can't be served.
+	b := &blobs{form: form}
+	values := form.Value[MultipartRequestPart]
+	if len(values) == 0 {
+		b.release()
+		return nil, &Error{
+			Code:    ErrInvalidRequest,
+			Message: "rpc: " + MultipartRequestPart + " part is missing",
+		}
+	}
+
+	var (
+		req = new(serverRequest)
+		v1  bool
+	)
+	if err = c.engine.Unmarshal([]byte(values[0]), req); err != nil {
+		b.release()
+		return nil, newParseError(req, err)
+	} else if v1, err = c.checkVersion(req); err != nil {
+		b.release()
+		return nil, err
+	}
+
+	res := c.newRequest(req, v1)
+	res.writer, res.encoder, res.blobs = w, c.encSel.Select(r), b
+	return res, nil
+}
+
+// Close releases parts of multipart request, so attached blobs can't be
+// read anymore.
+func (c *request) Close() error {
+	if c.blobs != nil {
+		c.blobs.release()
+	}
+	return nil
+}
+
+// Read counts bytes read from the body.
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// Read reads the attachment.
+func (b *Blob) Read(p []byte) (int, error) {
+	if b.r == nil {
+		return 0, ErrNoBlob
+	}
+	return b.r.Read(p)
+}
+
+// UnmarshalJSON decodes name of the part holding blob.
+func (b *Blob) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &b.Name)
+}
+
+// MarshalJSON encodes name of the part holding blob.
+func (b Blob) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Name)
+}
+
+// bind attaches parts to blobs referenced by args.
+func (b *blobs) bind(args interface{}) error {
+	return b.walk(reflect.ValueOf(args))
+}
+
+// walk looks for blobs in the value and attaches parts to them.
+func (b *blobs) walk(v reflect.Value) error {
+	if v.Type() == typeOfBlob && v.CanAddr() {
+		return b.attach(v.Addr().Interface().(*Blob))
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return b.walk(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				if err := b.walk(f); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := b.walk(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map values aren't addressable, so they are replaced by copies.
+		for _, key := range v.MapKeys() {
+			item := reflect.New(v.Type().Elem()).Elem()
+			item.Set(v.MapIndex(key))
+			if err := b.walk(item); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, item)
+		}
+	}
+	return nil
+}
+
+// attach opens part referenced by blob.
+func (b *blobs) attach(blob *Blob) error {
+	headers := b.form.File[blob.Name]
+	if len(headers) == 0 {
+		return &Error{
+			Code:    ErrBadParams,
+			Message: "rpc: part " + blob.Name + " is missing",
+		}
+	}
+
+	f, err := headers[0].Open()
+	if err != nil {
+		return &Error{
+			Code:     ErrInternal,
+			Message:  err.Error(),
+			Internal: err,
+		}
+	}
+
+	b.mu.Lock()
+	b.files = append(b.files, f)
+	b.mu.Unlock()
+
+	blob.Filename = headers[0].Filename
+	blob.Size = headers[0].Size
+	blob.r = f
+	return nil
+}
+
+// release closes opened parts and removes temporary files.
+func (b *blobs) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, f := range b.files {
+		_ = f.Close()
+	}
+	b.files = nil
+	_ = b.form.RemoveAll()
+}
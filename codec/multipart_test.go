@@ -0,0 +1,147 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadArgs struct {
+	Name     string
+	Contract Blob
+	Extra    []*Blob
+}
+
+func newMultipartRequest(t *testing.T, envelope string, files map[string]string) *http.Request {
+	var (
+		body = new(bytes.Buffer)
+		mw   = multipart.NewWriter(body)
+	)
+
+	if envelope != "" {
+		require.NoError(t, mw.WriteField(MultipartRequestPart, envelope))
+	}
+	for name, data := range files {
+		fw, err := mw.CreateFormFile(name, name+".bin")
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, "/", body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestMultipartSuite(t *testing.T) {
+	t.Run("Multipart codec test suite", func(t *testing.T) {
+		t.Run("should attach blobs to params", func(t *testing.T) {
+			var (
+				rec   = httptest.NewRecorder()
+				codec = NewMultipart(DefaultEncoderSelector, 0)
+			)
+
+			req := newMultipartRequest(t, `{
+				"jsonrpc": "2.0",
+				"id": 1,
+				"method": "upload",
+				"params": {"name": "nns", "contract": "nef", "extra": ["manifest"]}
+			}`, map[string]string{
+				"nef":      "\x00\x01binary",
+				"manifest": "{}",
+			})
+
+			r, err := codec.NewRequest(rec, req)
+			require.NoError(t, err)
+			require.Equal(t, "upload", r.Method())
+
+			args := new(uploadArgs)
+			require.NoError(t, r.ReadRequest(args))
+			require.Equal(t, "nns", args.Name)
+
+			data, err := ioutil.ReadAll(&args.Contract)
+			require.NoError(t, err)
+			require.Equal(t, "\x00\x01binary", string(data))
+			require.Equal(t, "nef.bin", args.Contract.Filename)
+			require.EqualValues(t, 8, args.Contract.Size)
+
+			require.Len(t, args.Extra, 1)
+			data, err = ioutil.ReadAll(args.Extra[0])
+			require.NoError(t, err)
+			require.Equal(t, "{}", string(data))
+		})
+
+		t.Run("should release blobs when closed", func(t *testing.T) {
+			// Parts exceeding 1 byte are stored in temporary files.
+			req := newMultipartRequest(t, `{"jsonrpc": "2.0", "id": 1, "method": "upload", "params": {"contract": "nef"}}`,
+				map[string]string{"nef": "\x00\x01binary"})
+
+			r, err := NewMultipart(DefaultEncoderSelector, 1).NewRequest(httptest.NewRecorder(), req)
+			require.NoError(t, err)
+
+			args := new(uploadArgs)
+			require.NoError(t, r.ReadRequest(args))
+
+			f, ok := args.Contract.r.(*os.File)
+			require.True(t, ok)
+
+			require.NoError(t, r.(io.Closer).Close())
+			_, err = ioutil.ReadAll(&args.Contract)
+			require.Error(t, err)
+			_, err = os.Stat(f.Name())
+			require.True(t, os.IsNotExist(err))
+		})
+
+		t.Run("should limit request body", func(t *testing.T) {
+			req := newMultipartRequest(t, `{"jsonrpc": "2.0", "id": 1, "method": "upload", "params": {"contract": "nef"}}`,
+				map[string]string{"nef": strings.Repeat("a", 1024)})
+
+			_, err := NewMultipart(DefaultEncoderSelector, 0, WithMultipartLimit(512)).NewRequest(httptest.NewRecorder(), req)
+			require.IsType(t, (*misc.HTTPError)(nil), err)
+			require.Equal(t, http.StatusRequestEntityTooLarge, err.(*misc.HTTPError).Code)
+		})
+
+		t.Run("should fail on missing blob", func(t *testing.T) {
+			req := newMultipartRequest(t, `{"jsonrpc": "2.0", "id": 1, "method": "upload", "params": {"contract": "nef"}}`, nil)
+
+			r, err := NewMultipart(DefaultEncoderSelector, 0).NewRequest(httptest.NewRecorder(), req)
+			require.NoError(t, err)
+
+			err = r.ReadRequest(new(uploadArgs))
+			require.IsType(t, (*Error)(nil), err)
+			require.Equal(t, ErrBadParams, err.(*Error).Code)
+		})
+
+		t.Run("should fail on bad request", func(t *testing.T) {
+			codec := NewMultipart(DefaultEncoderSelector, 0)
+
+			_, err := codec.NewRequest(httptest.NewRecorder(), newMultipartRequest(t, "", nil))
+			require.Error(t, err)
+
+			_, err = codec.NewRequest(httptest.NewRecorder(), newMultipartRequest(t, `{"jsonrpc": "2.0"`, nil))
+			require.Error(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			_, err = codec.NewRequest(httptest.NewRecorder(), req)
+			require.Error(t, err)
+		})
+
+		t.Run("should fail reading unattached blob", func(t *testing.T) {
+			_, err := new(Blob).Read(make([]byte, 1))
+			require.Equal(t, ErrNoBlob, err)
+		})
+	})
+}
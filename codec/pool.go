@@ -0,0 +1,76 @@
+package codec
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity of buffers starting from which they
+// aren't returned to the pool, so a single huge response doesn't stay in
+// memory.
+const maxPooledBuffer = 1 << 20
+
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+
+	serverRequestPool = sync.Pool{
+		New: func() interface{} { return new(serverRequest) },
+	}
+
+	serverResponsePool = sync.Pool{
+		New: func() interface{} { return new(serverResponse) },
+	}
+
+	// releasedRequest replaces request returned to the pool, it has no id,
+	// so nothing is written for it. It must not be modified.
+	releasedRequest = new(serverRequest)
+)
+
+// getBuffer returns empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer returns buffer to the pool, its data must not be used anymore.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// getServerRequest returns empty serverRequest from the pool.
+func getServerRequest() *serverRequest {
+	return serverRequestPool.Get().(*serverRequest)
+}
+
+// putServerRequest returns serverRequest to the pool, members are cleared,
+// so values decoded into them, e.g. params, aren't reused.
+func putServerRequest(req *serverRequest) {
+	*req = serverRequest{}
+	serverRequestPool.Put(req)
+}
+
+// getServerResponse returns serverResponse from the pool.
+func getServerResponse() *serverResponse {
+	return serverResponsePool.Get().(*serverResponse)
+}
+
+// putServerResponse returns serverResponse to the pool, so reply and error
+// aren't referenced anymore.
+func putServerResponse(res *serverResponse) {
+	*res = serverResponse{}
+	serverResponsePool.Put(res)
+}
+
+// release returns decoded request to the pool once its response is written,
+// later writes are ignored.
+func (c *request) release() {
+	if c.request != releasedRequest {
+		putServerRequest(c.request)
+		c.request = releasedRequest
+	}
+}
@@ -0,0 +1,45 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSuite(t *testing.T) {
+	t.Run("Pool test suite", func(t *testing.T) {
+		t.Run("should release request once response is written", func(t *testing.T) {
+			newRequest := func(body string) (Request, *httptest.ResponseRecorder) {
+				rec := httptest.NewRecorder()
+				req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+				require.NoError(t, err)
+
+				r, err := NewCodec().NewRequest(rec, req)
+				require.NoError(t, err)
+				return r, rec
+			}
+
+			first, rec := newRequest(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1, 2]}`)
+			first.WriteResponse(3)
+			first.WriteError(http.StatusOK, &Error{Code: ErrServer, Message: "late"})
+
+			res := new(testResponse)
+			dec := json.NewDecoder(bytes.NewReader(rec.Body.Bytes()))
+			require.NoError(t, dec.Decode(res))
+			require.Equal(t, `3`, string(res.Result))
+			require.False(t, dec.More())
+
+			// Released request is reused, envelope of the first one must
+			// stay intact.
+			second, _ := newRequest(`{"jsonrpc": "2.0", "id": 2, "method": "mul", "params": [3, 4]}`)
+			require.Equal(t, `[1, 2]`, string(first.Envelope().Params))
+			require.Equal(t, "sum", first.Method())
+			require.Equal(t, `[3, 4]`, string(second.Envelope().Params))
+		})
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/nspcc-dev/jsonrpc/misc"
 )
@@ -50,15 +51,43 @@ type (
 	}
 
 	// Interface codec creates a CodecRequest to process each request.
+	// NewRequest is HTTP adapter, MessageCodec decodes requests received
+	// with any other transport.
 	Interface interface {
+		MessageCodec
 		NewRequest(http.ResponseWriter, *http.Request) (Request, error)
 	}
 
+	// Envelope represents parsed request envelope, params are left
+	// encoded until Request.ReadRequest is called.
+	Envelope struct {
+		// Protocol version.
+		Version string
+
+		// The request id, empty for notifications.
+		ID string
+
+		// The name of the method to be invoked.
+		Method string
+
+		// Encoded method params.
+		Params []byte
+
+		// Meta can be used to pass additional data along with the request.
+		Meta map[string]interface{}
+
+		// safe is set for requests that came with HTTP GET.
+		safe bool
+	}
+
 	// Request decodes a request and encodes a response using a specific
-	// serialization scheme.
+	// serialization scheme. Requests holding resources, e.g. files of
+	// multipart request, implement io.Closer, they are closed once served.
 	Request interface {
 		// HandleError from input and request instance
 		HandleError(err error) bool
+		// Returns parsed request envelope.
+		Envelope() *Envelope
 		// Reads the request and returns the RPC method name.
 		Method() string
 		// Reads the request filling the RPC method args.
@@ -69,16 +98,50 @@ type (
 		WriteError(status int, err error)
 	}
 
+	// ResponseCodec is implemented by codecs that can answer requests
+	// decoded by other codecs, it's used to respond in format the client
+	// asked for with Accept header.
+	ResponseCodec interface {
+		// NewResponder returns Responder writing responses to the request
+		// with env in codec's format.
+		NewResponder(w http.ResponseWriter, r *http.Request, env *Envelope) (Responder, error)
+	}
+
+	// Responder writes responses to the request.
+	Responder interface {
+		// HandleError writes error response, true is returned when err
+		// isn't nil.
+		HandleError(err error) bool
+		// Writes the response using the RPC method reply.
+		WriteResponse(interface{})
+		// Writes an error produced by the server.
+		WriteError(status int, err error)
+	}
+
 	// codec creates a Request to process each request.
 	codec struct {
-		encSel EncoderSelector
+		encSel  EncoderSelector
+		engine  JSONEngine
+		get     *GETConfig // nil when GET requests aren't allowed
+		v1      v1Mode     // how JSON-RPC 1.0 requests are handled
+		maxBody int64      // limit of multipart request body
 	}
 
+	// Option configures codec.
+	Option func(*codec)
+
 	// request decodes and encodes a single request.
 	request struct {
-		writer  http.ResponseWriter
-		request *serverRequest
-		encoder Encoder
+		writer   http.ResponseWriter // nil for requests decoded from messages
+		send     func([]byte)        // receives encoded message responses
+		engine   JSONEngine
+		request  *serverRequest
+		envelope *Envelope
+		encoder  Encoder
+		get      *getRequest  // set for requests that came with HTTP GET
+		events   *eventStream // set when client asked for event stream
+		blobs    *blobs       // parts of multipart request
+		v1       bool         // request is served with JSON-RPC 1.0
 	}
 )
 
@@ -86,24 +149,51 @@ type (
 const Version = "2.0"
 
 // NewCustom returns a new JSON codec based on passed encoder selector.
-func NewCustom(encSel EncoderSelector) Interface {
-	return &codec{encSel: encSel}
+func NewCustom(encSel EncoderSelector, opts ...Option) Interface {
+	c := &codec{encSel: encSel, engine: StdJSON}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // NewCodec returns a new JSON codec.
-func NewCodec() Interface {
-	return NewCustom(DefaultEncoderSelector)
+func NewCodec(opts ...Option) Interface {
+	return NewCustom(DefaultEncoderSelector, opts...)
 }
 
 // NewRequest returns a Request.
 func (c *codec) NewRequest(w http.ResponseWriter, r *http.Request) (Request, error) {
-	return newCodecRequest(w, r, c.encSel.Select(r))
+	if r.Method == http.MethodGet && c.get != nil {
+		return c.newGETRequest(w, r, c.encSel.Select(r))
+	}
+	return c.newCodecRequest(w, r, c.encSel.Select(r))
+}
+
+// NewResponder returns Responder writing JSON responses to the request
+// decoded by other codec, request id must be a number.
+func (c *codec) NewResponder(w http.ResponseWriter, r *http.Request, env *Envelope) (Responder, error) {
+	req := &serverRequest{Version: Version, Method: env.Method}
+	if env.ID != "" {
+		req.ID = new(json.Number)
+		if err := c.engine.Unmarshal([]byte(env.ID), req.ID); err != nil {
+			return nil, &Error{
+				Code:    ErrInvalidRequest,
+				Message: "rpc: id must be a number",
+			}
+		}
+	}
+
+	res := c.newRequest(req, env.Version == Version1)
+	res.writer, res.encoder = w, c.encSel.Select(r)
+	return res, nil
 }
 
 // newCodecRequest returns a new Request.
-func newCodecRequest(w http.ResponseWriter, r *http.Request, encoder Encoder) (Request, error) {
+func (c *codec) newCodecRequest(w http.ResponseWriter, r *http.Request, encoder Encoder) (Request, error) {
 	var (
-		req = new(serverRequest)
+		req = getServerRequest()
+		v1  bool
 		err error
 	)
 
@@ -119,22 +209,94 @@ func newCodecRequest(w http.ResponseWriter, r *http.Request, encoder Encoder) (R
 			Message: "rpc: POST method required, received " + r.Method,
 		}
 		// return &request{request: req, err: err, encoder: encoder}
-	} else if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+	} else if err = c.engine.NewDecoder(r.Body).Decode(&req); err != nil {
 		// Decode the request body and check if RPC method is valid.
-		return nil, &Error{
-			Code:     ErrParse,
-			Message:  err.Error(),
-			Data:     req,
-			Internal: err,
-		}
-	} else if req.Version != Version {
-		return nil, &Error{
+		return nil, newParseError(req, err)
+	} else if v1, err = c.checkVersion(req); err != nil {
+		return nil, err
+	}
+
+	res := c.newRequest(req, v1)
+	res.writer, res.encoder = w, encoder
+	// Notifications have no response to stream.
+	if req.ID != nil && acceptsEvents(r) {
+		res.events = &eventStream{w: w}
+	}
+	return res, nil
+}
+
+// newParseError returns error for request that can't be decoded.
+func newParseError(req *serverRequest, err error) error {
+	return &Error{
+		Code:     ErrParse,
+		Message:  err.Error(),
+		Data:     req,
+		Internal: err,
+	}
+}
+
+// checkVersion returns error when request has unsupported protocol version,
+// true is returned for requests served with JSON-RPC 1.0.
+func (c *codec) checkVersion(req *serverRequest) (bool, error) {
+	switch {
+	case c.v1 == v1Only, c.v1 == v1Detect && req.Version == "":
+		return true, nil
+	case req.Version != Version:
+		return false, &Error{
 			Code:    ErrInvalidRequest,
 			Message: "jsonrpc must be " + Version,
 			Data:    req,
 		}
 	}
-	return &request{writer: w, request: req, encoder: encoder}, nil
+	return false, nil
+}
+
+// newRequest returns request of decoded envelope, transport specific fields
+// are set by the caller.
+func (c *codec) newRequest(req *serverRequest, v1 bool) *request {
+	res := &request{
+		engine:   c.engine,
+		request:  req,
+		envelope: newEnvelope(req),
+		encoder:  DefaultEncoder,
+		v1:       v1,
+	}
+	if v1 {
+		res.envelope.Version = Version1
+	}
+	return res
+}
+
+// newEnvelope returns Envelope of the request.
+func newEnvelope(req *serverRequest) *Envelope {
+	env := &Envelope{
+		Version: string(req.Version),
+		Method:  req.Method,
+		Params:  req.Params,
+	}
+	if req.ID != nil {
+		env.ID = req.ID.String()
+	}
+	return env
+}
+
+// IDValue returns id of the envelope as uint64 or int64 when it's a number,
+// so codecs with typed ids encode it as number, other ids are returned as
+// string.
+func (e *Envelope) IDValue() interface{} {
+	if v, err := strconv.ParseUint(e.ID, 10, 64); err == nil {
+		return v
+	} else if v, err := strconv.ParseInt(e.ID, 10, 64); err == nil {
+		return v
+	}
+	return e.ID
+}
+
+// Safe reports whether request came with HTTP GET, such requests must be
+// served only by methods registered as safe. Server reads it before hooks
+// are run, so they can't change it.
+func (e *Envelope) Safe() bool {
+	return e.safe
 }
 
 func (c *request) HandleError(err error) bool {
@@ -144,8 +306,7 @@ func (c *request) HandleError(err error) bool {
 	case *misc.HTTPError:
 		c.WriteError(err.Code, err)
 	case *Error:
-		switch err.Internal.(type) {
-		case *json.SyntaxError, *json.UnmarshalTypeError:
+		if err.Internal != nil && c.engine.IsDecodeError(err.Internal) {
 			err.Message = "cannot unmarshal request"
 		}
 		c.WriteError(err.Code, err)
@@ -156,11 +317,16 @@ func (c *request) HandleError(err error) bool {
 	return true
 }
 
+// Envelope returns the parsed envelope of the current request.
+func (c *request) Envelope() *Envelope {
+	return c.envelope
+}
+
 // Method returns the RPC method for the current request.
 //
 // The method uses a dotted notation as in "Service.Method".
 func (c *request) Method() string {
-	return c.request.Method
+	return c.envelope.Method
 }
 
 // ReadRequest fills the request object for the RPC method.
@@ -177,76 +343,117 @@ func (c *request) Method() string {
 // generated. The names MUST match exactly, including
 // case, to the method's expected parameters.
 func (c *request) ReadRequest(args interface{}) error {
-	if c.request.Params != nil {
-		// Note: if c.request.Params is nil it's not an error, it's an optional member.
+	if params := c.envelope.Params; params != nil {
+		// Note: if params is nil it's not an error, it's an optional member.
 		// JSON params structured object. Unmarshal to the args object.
-		if err := json.Unmarshal(c.request.Params, args); err != nil {
+		if err := c.engine.Unmarshal(params, args); err != nil {
 			// Clearly JSON params is not a structured object,
 			// fallback and attempt an unmarshal with JSON params as
 			// array value and RPC params is struct. Unmarshal into
 			// array containing the request struct.
-			if err = json.Unmarshal(c.request.Params, &args); err != nil {
+			if err = c.engine.Unmarshal(params, &args); err != nil {
 				return &Error{
 					Code:     ErrBadParams,
 					Message:  err.Error(),
-					Data:     c.request.Params,
+					Data:     json.RawMessage(params),
 					Internal: err,
 				}
 			}
 		}
 	}
+	if c.blobs != nil {
+		return c.blobs.bind(args)
+	}
 	return nil
 }
 
 // WriteResponse encodes the response and writes it to the ResponseWriter.
 func (c *request) WriteResponse(reply interface{}) {
-	res := &serverResponse{
-		Version: Version,
-		Result:  reply,
-		ID:      c.request.ID,
+	res := getServerResponse()
+	res.Version, res.Result, res.ID = Version, reply, c.request.ID
+
+	if it := newIterator(reply); it != nil {
+		c.writeStreamed(res, it)
+	} else {
+		c.writeServerResponse(res)
 	}
-	c.writeServerResponse(res)
+	putServerResponse(res)
+	c.release()
 }
 
 //
 func (c *request) WriteError(status int, err error) {
-	res := &serverResponse{
-		Version: Version,
-		ID:      c.request.ID,
-	}
+	res := getServerResponse()
+	res.Version, res.ID, res.Error = Version, c.request.ID, newError(err)
 
+	c.writeServerResponse(res)
+	putServerResponse(res)
+	c.release()
+}
+
+// newError converts err into Error.
+func newError(err error) *Error {
 	switch err := err.(type) {
 	case *Error:
-		res.Error = err
+		return err
 	default:
-		res.Error = &Error{
+		return &Error{
 			Code:    ErrServer,
 			Message: err.Error(),
 		}
 	}
-
-	c.writeServerResponse(res)
 }
 
 func (c *request) writeServerResponse(res *serverResponse) {
+	if c.writer == nil {
+		c.sendServerResponse(res)
+		return
+	}
+
 	c.writer.Header().Set(misc.HeaderXContentTypeOptions, "nosniff")
+	if c.events != nil {
+		c.writeEvent(res)
+		return
+	} else if c.get != nil {
+		c.writeCacheable(res)
+		return
+	}
+
 	// ID is null for notifications and they don't have a response.
 	if c.request.ID != nil {
-		c.writer.Header().Set(misc.HeaderContentType, misc.MIMEApplicationJSONCharsetUTF8)
-		encoder := json.NewEncoder(c.encoder.Encode(c.writer))
+		buf := getBuffer()
+		defer putBuffer(buf)
 
 		// Not sure in which case will this happen. But seems harmless.
-		if err := encoder.Encode(res); err != nil {
+		if err := c.engine.NewEncoder(buf).Encode(c.response(res)); err != nil {
+			WriteError(c.writer, err)
+			return
+		}
+
+		c.writer.Header().Set(misc.HeaderContentType, misc.MIMEApplicationJSONCharsetUTF8)
+		if err := WriteEncoded(c.writer, c.encoder, buf.Bytes()); err != nil {
 			WriteError(c.writer, err)
 		}
 	}
 }
 
-// WriteError to ResponseWriter
+// WriteError to ResponseWriter, status is taken from *misc.HTTPError and
+// request body exceeding the limit is answered with 413.
 func WriteError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch err := err.(type) {
+	case *misc.HTTPError:
+		status = err.Code
+	case *Error:
+		if err.Internal == ErrBodyTooLarge {
+			status = http.StatusRequestEntityTooLarge
+		}
+	}
+
 	w.Header().Set(misc.HeaderXContentTypeOptions, "nosniff")
 	w.Header().Set(misc.HeaderContentType, misc.MIMEApplicationJSONCharsetUTF8)
-	w.WriteHeader(http.StatusBadRequest)
+	w.Header().Del(misc.HeaderContentLength)
+	w.WriteHeader(status)
 	_, _ = fmt.Fprintf(w, `{"jsonrpc":%q,"id":1,"error":{"code":%d,"message":%q}}`,
 		Version,
 		ErrInvalidRequest,
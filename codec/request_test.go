@@ -152,6 +152,18 @@ func TestCodecSuite(t *testing.T) {
 			require.Equal(t, "", args)
 		})
 
+		t.Run("should return typed envelope id", func(t *testing.T) {
+			for id, v := range map[string]interface{}{
+				"1":                    uint64(1),
+				"-1":                   int64(-1),
+				"18446744073709551615": uint64(18446744073709551615),
+				"1.5":                  "1.5",
+				"abc":                  "abc",
+			} {
+				require.Equal(t, v, (&Envelope{ID: id}).IDValue(), id)
+			}
+		})
+
 		t.Run("HandleError suite", func(t *testing.T) {
 			t.Run("should be false for nil error", func(t *testing.T) {
 				var (
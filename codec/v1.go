@@ -0,0 +1,66 @@
+package codec
+
+import "encoding/json"
+
+type (
+	// v1Mode tells how codec handles JSON-RPC 1.0 requests.
+	v1Mode int
+
+	// serverResponseV1 represents a JSON-RPC 1.0 response, result and
+	// error are always present and the one not used is null.
+	serverResponseV1 struct {
+		// This must be the same id as the request it is responding to.
+		ID *json.Number `json:"id"`
+
+		// An Error object if there was an error invoking the method.
+		Error *Error `json:"error"`
+
+		// The Object that was returned by the invoked method.
+		Result interface{} `json:"result"`
+	}
+)
+
+// Version1 of json-rpc protocol
+const Version1 = "1.0"
+
+const (
+	// v1Reject fails requests without jsonrpc member.
+	v1Reject v1Mode = iota
+	// v1Detect serves requests without jsonrpc member with JSON-RPC 1.0.
+	v1Detect
+	// v1Only serves every request with JSON-RPC 1.0.
+	v1Only
+)
+
+// WithVersion1 allows JSON-RPC 1.0 requests, they are detected by missing
+// jsonrpc member and answered in JSON-RPC 1.0 format.
+func WithVersion1() Option {
+	return func(c *codec) {
+		c.v1 = v1Detect
+	}
+}
+
+// NewVersion1 returns a new JSON-RPC 1.0 codec, every request is answered
+// in JSON-RPC 1.0 format regardless of jsonrpc member. It's meant to be
+// registered for Content-Type used by JSON-RPC 1.0 clients.
+func NewVersion1(encSel EncoderSelector, opts ...Option) Interface {
+	c := &codec{encSel: encSel, engine: StdJSON}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.v1 = v1Only
+	return c
+}
+
+// response returns value the response is encoded from, it depends on
+// protocol version of the request.
+func (c *request) response(res *serverResponse) interface{} {
+	if !c.v1 {
+		return res
+	}
+	return &serverResponseV1{
+		ID:     res.ID,
+		Error:  res.Error,
+		Result: res.Result,
+	}
+}
@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion1Suite(t *testing.T) {
+	t.Run("JSON-RPC 1.0 codec test suite", func(t *testing.T) {
+		serve := func(t *testing.T, codec Interface, body string, reply interface{}, err error) map[string]json.RawMessage {
+			rec := httptest.NewRecorder()
+			req, e := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			require.NoError(t, e)
+
+			r, e := codec.NewRequest(rec, req)
+			require.NoError(t, e)
+
+			if err != nil {
+				r.WriteError(http.StatusOK, err)
+			} else {
+				r.WriteResponse(reply)
+			}
+
+			var res map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			return res
+		}
+
+		t.Run("should answer with result and null error", func(t *testing.T) {
+			res := serve(t, NewVersion1(DefaultEncoderSelector), `{"id": 1, "method": "sum", "params": [1, 2]}`, 3, nil)
+			require.Len(t, res, 3)
+			require.Equal(t, `1`, string(res["id"]))
+			require.Equal(t, `3`, string(res["result"]))
+			require.Equal(t, `null`, string(res["error"]))
+		})
+
+		t.Run("should answer with error and null result", func(t *testing.T) {
+			res := serve(t, NewVersion1(DefaultEncoderSelector), `{"id": 1, "method": "sum", "params": []}`, nil, errors.New("fail"))
+			require.Len(t, res, 3)
+			require.Equal(t, `null`, string(res["result"]))
+			require.Contains(t, string(res["error"]), `"message":"fail"`)
+		})
+
+		t.Run("should detect version", func(t *testing.T) {
+			codec := NewCodec(WithVersion1())
+
+			res := serve(t, codec, `{"id": 1, "method": "sum"}`, 3, nil)
+			require.NotContains(t, res, "jsonrpc")
+			require.Equal(t, `null`, string(res["error"]))
+
+			res = serve(t, codec, `{"jsonrpc": "2.0", "id": 1, "method": "sum"}`, 3, nil)
+			require.Contains(t, res, "jsonrpc")
+			require.NotContains(t, res, "error")
+		})
+
+		t.Run("should reject unknown version", func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "3.0", "id": 1, "method": "sum"}`))
+			require.NoError(t, err)
+
+			_, err = NewCodec(WithVersion1()).NewRequest(httptest.NewRecorder(), req)
+			require.Error(t, err)
+		})
+
+		t.Run("should decode messages", func(t *testing.T) {
+			var sent []byte
+			reqs, err := NewCodec(WithVersion1()).NewMessage([]byte(`{"id": 1, "method": "sum", "params": [1]}`), func(msg []byte) {
+				sent = msg
+			})
+			require.NoError(t, err)
+			require.Len(t, reqs, 1)
+			require.Equal(t, Version1, reqs[0].Envelope().Version)
+
+			reqs[0].WriteResponse(1)
+
+			var res map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(sent, &res))
+			require.Equal(t, `null`, string(res["error"]))
+			require.Equal(t, `1`, string(res["result"]))
+		})
+	})
+}
@@ -0,0 +1,235 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+)
+
+type (
+	// ConnConfig holds settings shared by persistent connection transports.
+	ConnConfig struct {
+		// Codec decodes received messages, JSON codec is used by default.
+		Codec codec.MessageCodec
+
+		// ReadLimit is the maximum size of received message in bytes.
+		ReadLimit int64
+
+		// MaxInFlight limits the number of messages served concurrently,
+		// messages received when it's reached wait for served ones to
+		// finish. Reading from the connection isn't paused, so responses to
+		// calls made by handlers with Peer are received.
+		MaxInFlight int
+
+		// MaxQueued limits the number of messages waiting for served ones,
+		// requests of messages received when it's reached are answered
+		// with ErrTooManyRequests.
+		MaxQueued int
+
+		// SendQueue is the size of outgoing messages queue.
+		SendQueue int
+	}
+
+	// stream is a message-oriented connection of persistent transport.
+	stream interface {
+		ReadMessage() ([]byte, error)
+		WriteMessage(msg []byte) error
+		Close() error
+	}
+
+	// conn serves messages received with persistent connection.
+	conn struct {
+		rpc    *RPC
+		cdc    codec.MessageCodec
+		stream stream
+		subs   *subscriptions
+		peer   *Peer
+		ctx    context.Context
+		cancel context.CancelFunc
+		out    chan []byte
+		sem    chan struct{}
+		queue  chan struct{} // held by messages until they are served
+		wg     sync.WaitGroup
+		read   chan struct{} // closed when reading stops
+		drain  chan struct{} // closed when nothing more will be queued
+		done   chan struct{} // closed when writeLoop exits
+	}
+)
+
+const (
+	// DefaultReadLimit is used when ConnConfig.ReadLimit isn't set.
+	DefaultReadLimit = 1 << 20
+	// DefaultMaxInFlight is used when ConnConfig.MaxInFlight isn't set.
+	DefaultMaxInFlight = 64
+	// DefaultMaxQueued is used when ConnConfig.MaxQueued isn't set.
+	DefaultMaxQueued = 64
+	// DefaultSendQueue is used when ConnConfig.SendQueue isn't set.
+	DefaultSendQueue = 64
+)
+
+// ErrTooManyRequests is the error of requests received when queue of the
+// connection is full.
+const ErrTooManyRequests = Error("too many requests")
+
+// withDefaults returns config with unset fields filled by defaults.
+func (cfg ConnConfig) withDefaults() ConnConfig {
+	if cfg.Codec == nil {
+		cfg.Codec = codec.NewCodec()
+	}
+	if cfg.ReadLimit <= 0 {
+		cfg.ReadLimit = DefaultReadLimit
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = DefaultMaxInFlight
+	}
+	if cfg.MaxQueued <= 0 {
+		cfg.MaxQueued = DefaultMaxQueued
+	}
+	if cfg.SendQueue <= 0 {
+		cfg.SendQueue = DefaultSendQueue
+	}
+	return cfg
+}
+
+// newConn creates connection serving messages of the stream.
+func (s *RPC) newConn(ctx context.Context, st stream, cfg ConnConfig) *conn {
+	cfg = cfg.withDefaults()
+	c := &conn{
+		rpc:    s,
+		cdc:    cfg.Codec,
+		stream: st,
+		subs:   newSubscriptionRegistry(),
+		out:    make(chan []byte, cfg.SendQueue),
+		sem:    make(chan struct{}, cfg.MaxInFlight),
+		queue:  make(chan struct{}, cfg.MaxInFlight+cfg.MaxQueued),
+		read:   make(chan struct{}),
+		drain:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.peer = newPeer(c)
+	return c
+}
+
+// serve reads and dispatches messages until the connection is closed or
+// its context is done. Responses to in-flight messages are written before
+// it returns, unless the context is done.
+func (c *conn) serve() {
+	go c.writeLoop()
+
+	for {
+		msg, err := c.stream.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		// Responses to server requests aren't limited, handlers can wait
+		// for them.
+		if res, ok := c.cdc.NewResponse(msg); ok {
+			c.peer.resolve(res)
+			continue
+		} else if c.ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case c.queue <- struct{}{}:
+			c.wg.Add(1)
+			go c.handle(msg)
+		default:
+			c.reject(msg)
+		}
+	}
+
+	// Handlers waiting for responses can't get them anymore.
+	close(c.read)
+	c.wg.Wait()
+	close(c.drain)
+	<-c.done
+	c.close()
+}
+
+// handle serves single message once the number of served ones is below
+// MaxInFlight.
+func (c *conn) handle(msg []byte) {
+	defer c.wg.Done()
+	defer func() { <-c.queue }()
+
+	select {
+	case c.sem <- struct{}{}:
+	case <-c.ctx.Done():
+		return
+	}
+	defer func() { <-c.sem }()
+
+	in := &inbound{mu: new(sync.Mutex), conn: c}
+	_ = c.rpc.ServeMessage(context.WithValue(c.ctx, inboundKey{}, in), c.cdc, msg, c.send)
+
+	// Responses are queued already, notifications can be sent.
+	in.start()
+}
+
+// reject answers requests of the message with ErrTooManyRequests without
+// serving them.
+func (c *conn) reject(msg []byte) {
+	reqs, err := c.cdc.NewMessage(msg, c.send)
+	if err != nil {
+		return
+	}
+	for _, req := range reqs {
+		req.HandleError(ErrTooManyRequests)
+	}
+}
+
+// send queues message to be written, it blocks when queue is full.
+func (c *conn) send(msg []byte) {
+	select {
+	case c.out <- msg:
+	case <-c.ctx.Done():
+	}
+}
+
+// writeLoop writes queued messages until the connection is closed.
+func (c *conn) writeLoop() {
+	defer func() {
+		_ = c.stream.Close()
+		close(c.done)
+	}()
+
+	for {
+		select {
+		case msg := <-c.out:
+			if !c.write(msg) {
+				return
+			}
+		case <-c.drain:
+			for {
+				select {
+				case msg := <-c.out:
+					if !c.write(msg) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// write writes message to the stream, the connection is closed on failure.
+func (c *conn) write(msg []byte) bool {
+	if err := c.stream.WriteMessage(msg); err != nil {
+		c.close()
+		return false
+	}
+	return true
+}
+
+// close stops serving the connection.
+func (c *conn) close() {
+	c.cancel()
+}
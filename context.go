@@ -0,0 +1,38 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// requestKey is the context key of HTTP request.
+type requestKey struct{}
+
+// withRequest returns context holding HTTP request.
+func withRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+// RequestFromContext returns HTTP request the call came with, it's nil for
+// calls received with other transports.
+func RequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestKey{}).(*http.Request)
+	return r
+}
+
+// stubRequest returns request passed to methods accepting *http.Request when
+// the call came without HTTP request. Its URL and headers are empty, but not
+// nil, so methods written for HTTP can read them.
+func stubRequest(ctx context.Context) *http.Request {
+	r := &http.Request{
+		Method:     http.MethodPost,
+		URL:        new(url.URL),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+	}
+	return r.WithContext(ctx)
+}
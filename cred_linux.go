@@ -0,0 +1,33 @@
+package jsonrpc
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredentials returns credentials of the process connected with the
+// socket using SO_PEERCRED.
+func peerCredentials(uc *net.UnixConn) (*Credentials, error) {
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ucred *syscall.Ucred
+		cerr  error
+	)
+	if err = raw.Control(func(fd uintptr) {
+		ucred, cerr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	} else if cerr != nil {
+		return nil, cerr
+	}
+
+	return &Credentials{
+		PID: ucred.Pid,
+		UID: ucred.Uid,
+		GID: ucred.Gid,
+	}, nil
+}
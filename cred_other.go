@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package jsonrpc
+
+import "net"
+
+// peerCredentials isn't supported on this platform.
+func peerCredentials(*net.UnixConn) (*Credentials, error) {
+	return nil, ErrNoCredentials
+}
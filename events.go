@@ -0,0 +1,53 @@
+package jsonrpc
+
+import (
+	"context"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+)
+
+type (
+	// EventStream sends incremental results of the call ahead of its
+	// response, e.g. as Server-Sent Events when client accepts
+	// "text/event-stream". Every result is sent as ChunkMethod
+	// notification.
+	EventStream struct {
+		req codec.StreamRequest
+	}
+
+	// eventStreamKey is the context key of event stream.
+	eventStreamKey struct{}
+)
+
+const (
+	// ChunkMethod is the method of notifications carrying incremental
+	// results.
+	ChunkMethod = "chunk"
+)
+
+// withEventStream returns context holding event stream of the request, when
+// client asked for streamed response.
+func withEventStream(ctx context.Context, req codec.Request) context.Context {
+	if sr, ok := req.(codec.StreamRequest); ok && sr.Streaming() {
+		return context.WithValue(ctx, eventStreamKey{}, &EventStream{req: sr})
+	}
+	return ctx
+}
+
+// streaming reports whether the request is answered with event stream.
+func streaming(req codec.Request) bool {
+	sr, ok := req.(codec.StreamRequest)
+	return ok && sr.Streaming()
+}
+
+// EventStreamFromContext returns event stream of the call, it's nil when
+// client didn't ask for streamed response or the codec doesn't support it.
+func EventStreamFromContext(ctx context.Context) *EventStream {
+	st, _ := ctx.Value(eventStreamKey{}).(*EventStream)
+	return st
+}
+
+// Send sends incremental result, it fails after the response is written.
+func (s *EventStream) Send(result interface{}) error {
+	return s.req.WriteNotification(ChunkMethod, result)
+}
@@ -0,0 +1,120 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/codec/msgpack"
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvents returns data of events written to the recorder.
+func readEvents(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	var events []string
+	for _, event := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n") {
+		require.True(t, strings.HasPrefix(event, "data: "), event)
+		events = append(events, strings.TrimPrefix(event, "data: "))
+	}
+	return events
+}
+
+func TestEventStreamSuite(t *testing.T) {
+	t.Run("Event stream test suite", func(t *testing.T) {
+		var (
+			srv  = newTestRPC()
+			sent = make(chan error, 1)
+		)
+		require.NoError(t, srv.AddMethod("export", func(ctx context.Context, args int, reply *int) error {
+			st := EventStreamFromContext(ctx)
+			if st == nil {
+				*reply = -1
+				return nil
+			}
+			for i := 0; i < args; i++ {
+				if err := st.Send(i); err != nil {
+					return err
+				}
+			}
+			*reply = args
+			go func() { sent <- st.Send(args) }()
+			return nil
+		}))
+
+		serve := func(body, accept string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set(misc.HeaderContentType, misc.MIMEApplicationJSON)
+			req.Header.Set(misc.HeaderAccept, accept)
+			srv.ServeHTTP(rec, req)
+			return rec
+		}
+
+		t.Run("should stream results as events", func(t *testing.T) {
+			rec := serve(`{"jsonrpc": "2.0", "id": 1, "method": "export", "params": 2}`,
+				"application/json, text/event-stream; q=0.5")
+			require.Equal(t, misc.MIMETextEventStream, rec.Header().Get(misc.HeaderContentType))
+			require.True(t, rec.Flushed)
+
+			events := readEvents(t, rec)
+			require.Len(t, events, 3)
+
+			for i := 0; i < 2; i++ {
+				n := new(testCall)
+				require.NoError(t, json.Unmarshal([]byte(events[i]), n))
+				require.Equal(t, ChunkMethod, n.Method)
+				require.Equal(t, string(mustMarshal(t, i)), string(n.Params))
+			}
+
+			res := new(wsResponse)
+			require.NoError(t, json.Unmarshal([]byte(events[2]), res))
+			require.Equal(t, "1", res.ID.String())
+			require.Equal(t, `2`, string(res.Result))
+
+			// Nothing can be sent after the response.
+			require.Error(t, <-sent)
+		})
+
+		t.Run("should stream errors", func(t *testing.T) {
+			rec := serve(`{"jsonrpc": "2.0", "id": 1, "method": "unknown"}`, misc.MIMETextEventStream)
+
+			events := readEvents(t, rec)
+			require.Len(t, events, 1)
+
+			res := new(wsResponse)
+			require.NoError(t, json.Unmarshal([]byte(events[0]), res))
+			require.NotNil(t, res.Error)
+		})
+
+		t.Run("should respond at once without event stream", func(t *testing.T) {
+			rec := serve(`{"jsonrpc": "2.0", "id": 1, "method": "export", "params": 2}`, misc.MIMEApplicationJSON)
+			require.Equal(t, misc.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(misc.HeaderContentType))
+
+			res := new(wsResponse)
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+			require.Equal(t, `-1`, string(res.Result))
+		})
+
+		t.Run("should stream results when Accept picks other codec", func(t *testing.T) {
+			srv.AddCodec(msgpack.NewCodec(codec.DefaultEncoderSelector), misc.MIMEApplicationMsgpack)
+
+			rec := serve(`{"jsonrpc": "2.0", "id": 1, "method": "export", "params": 1}`,
+				"application/msgpack, text/event-stream; q=0.5")
+			require.Equal(t, misc.MIMETextEventStream, rec.Header().Get(misc.HeaderContentType))
+
+			events := readEvents(t, rec)
+			require.Len(t, events, 2)
+
+			res := new(wsResponse)
+			require.NoError(t, json.Unmarshal([]byte(events[1]), res))
+			require.Equal(t, `1`, string(res.Result))
+			require.Error(t, <-sent)
+		})
+	})
+}
@@ -0,0 +1,132 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGETRPC(t *testing.T) *RPC {
+	srv := NewRPC()
+	srv.AddCodec(codec.NewCodec(codec.WithGET(codec.GETConfig{
+		Prefix: "/rpc",
+		MaxAge: time.Minute,
+	})), misc.MIMEApplicationJSON)
+
+	require.NoError(t, srv.AddSafeMethod("sum", sumMethod))
+	require.NoError(t, srv.AddMethod("send", func(ctx context.Context, args []int, reply *bool) error {
+		*reply = true
+		return nil
+	}))
+	return srv
+}
+
+func serveTestGET(t *testing.T, srv *RPC, url string, header http.Header) (*httptest.ResponseRecorder, *wsResponse) {
+	rec := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for k := range header {
+		req.Header.Set(k, header.Get(k))
+	}
+
+	require.NotPanics(t, func() { srv.ServeHTTP(rec, req) })
+
+	res := new(wsResponse)
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+	}
+	return rec, res
+}
+
+func TestGETSuite(t *testing.T) {
+	t.Run("GET test suite", func(t *testing.T) {
+		t.Run("should call method with query", func(t *testing.T) {
+			srv := newTestGETRPC(t)
+
+			rec, res := serveTestGET(t, srv, "/rpc?method=sum&params=[1,2]&id=7", nil)
+			require.Nil(t, res.Error)
+			require.Equal(t, "7", res.ID.String())
+			require.Equal(t, `3`, string(res.Result))
+			require.Equal(t, "public, max-age=60", rec.Header().Get(misc.HeaderCacheControl))
+			require.NotEmpty(t, rec.Header().Get(misc.HeaderETag))
+		})
+
+		t.Run("should call method with path", func(t *testing.T) {
+			srv := newTestGETRPC(t)
+
+			_, res := serveTestGET(t, srv, "/rpc/sum?params=[2,3]", nil)
+			require.Nil(t, res.Error)
+			require.Nil(t, res.ID)
+			require.Equal(t, `5`, string(res.Result))
+		})
+
+		t.Run("should respond not modified", func(t *testing.T) {
+			srv := newTestGETRPC(t)
+
+			rec, _ := serveTestGET(t, srv, "/rpc/sum?params=[1]", nil)
+			etag := rec.Header().Get(misc.HeaderETag)
+
+			rec, _ = serveTestGET(t, srv, "/rpc/sum?params=[1]", http.Header{
+				misc.HeaderIfNoneMatch: []string{`"other", ` + etag},
+			})
+			require.Equal(t, http.StatusNotModified, rec.Code)
+			require.Empty(t, rec.Body.String())
+
+			rec, _ = serveTestGET(t, srv, "/rpc/sum?params=[2]", http.Header{
+				misc.HeaderIfNoneMatch: []string{etag},
+			})
+			require.Equal(t, http.StatusOK, rec.Code)
+		})
+
+		t.Run("should reject unsafe method", func(t *testing.T) {
+			srv := newTestGETRPC(t)
+
+			rec, res := serveTestGET(t, srv, "/rpc/send?params=[1]", nil)
+			require.NotNil(t, res.Error)
+			require.Equal(t, ErrUnsafeMethod.Message, res.Error.Message)
+			require.Equal(t, "no-store", rec.Header().Get(misc.HeaderCacheControl))
+			require.Empty(t, rec.Header().Get(misc.HeaderETag))
+
+			// Hooks can't make the request look like POST one.
+			srv.AddHook(func(ctx context.Context, env *codec.Envelope) error {
+				*env = codec.Envelope{Version: env.Version, ID: env.ID, Method: env.Method, Params: env.Params}
+				return nil
+			})
+			_, res = serveTestGET(t, srv, "/rpc/send?params=[1]", nil)
+			require.NotNil(t, res.Error)
+			require.Equal(t, ErrUnsafeMethod.Message, res.Error.Message)
+
+			// Still available with POST.
+			res2 := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "send", "params": [1]}`)
+			require.Equal(t, `true`, string(res2.Result))
+		})
+
+		t.Run("should reject malformed request", func(t *testing.T) {
+			srv := newTestGETRPC(t)
+
+			for _, url := range []string{
+				"/rpc",
+				"/rpc/sum?id=abc",
+				"/rpc/sum?params=[1,",
+			} {
+				rec, _ := serveTestGET(t, srv, url, nil)
+				require.Equal(t, http.StatusBadRequest, rec.Code, url)
+			}
+		})
+
+		t.Run("should be disabled by default", func(t *testing.T) {
+			srv := newTestRPC()
+			require.NoError(t, srv.AddSafeMethod("sum", sumMethod))
+
+			rec, _ := serveTestGET(t, srv, "/?method=sum&params=[1]", nil)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	})
+}
@@ -2,4 +2,9 @@ module github.com/nspcc-dev/jsonrpc
 
 go 1.12
 
-require github.com/stretchr/testify v1.3.0
+require (
+	github.com/fxamacker/cbor/v2 v2.2.0
+	github.com/gorilla/websocket v1.4.2
+	github.com/stretchr/testify v1.3.0
+	github.com/vmihailenco/msgpack/v4 v4.3.12
+)
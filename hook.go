@@ -0,0 +1,34 @@
+package jsonrpc
+
+import (
+	"context"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+)
+
+type (
+	// Hook is called after request envelope is parsed, but before params
+	// are decoded. It can rewrite method name or params, put additional
+	// data into envelope Meta (passed to middleware with Call) or reject
+	// the request by returning an error. HTTP request the envelope came
+	// with can be obtained from context using RequestFromContext.
+	Hook func(ctx context.Context, env *codec.Envelope) error
+)
+
+// AddHook registers hooks, they are called in order they were registered.
+func (s *RPC) AddHook(h ...Hook) {
+	s.method.update(func(state *methodState) {
+		state.hooks = append(state.hooks, h...)
+	})
+}
+
+// runHooks calls hooks of the registry state until one of them returns an
+// error.
+func (state *methodState) runHooks(ctx context.Context, env *codec.Envelope) error {
+	for _, h := range state.hooks {
+		if err := h(ctx, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	cborv2 "github.com/fxamacker/cbor/v2"
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/codec/cbor"
+	"github.com/nspcc-dev/jsonrpc/codec/msgpack"
+	"github.com/stretchr/testify/require"
+	msgpackv4 "github.com/vmihailenco/msgpack/v4"
+)
+
+func TestHookSuite(t *testing.T) {
+	t.Run("Hook test suite", func(t *testing.T) {
+		t.Run("should rewrite method name", func(t *testing.T) {
+			var srv = newTestRPC()
+
+			srv.AddHook(func(ctx context.Context, env *codec.Envelope) error {
+				if env.Method == "legacySum" {
+					env.Method = "sum"
+				}
+				return nil
+			})
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "legacySum", "params": [1,2]}`)
+			require.Nil(t, res.Error)
+			require.Equal(t, `3`, string(res.Result))
+		})
+
+		t.Run("should reject request before params decoding", func(t *testing.T) {
+			var srv = newTestRPC()
+
+			srv.AddHook(func(ctx context.Context, env *codec.Envelope) error {
+				return &codec.Error{Code: codec.ErrNoMethod, Message: "Method not allowed"}
+			})
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": "bad"}`)
+			require.NotNil(t, res.Error)
+			require.Equal(t, codec.ErrNoMethod, res.Error.Code)
+			require.Equal(t, "Method not allowed", res.Error.Message)
+		})
+
+		t.Run("should pass meta to middleware", func(t *testing.T) {
+			var (
+				srv  = newTestRPC()
+				meta map[string]interface{}
+			)
+
+			srv.AddHook(func(ctx context.Context, env *codec.Envelope) error {
+				require.Equal(t, "1", env.ID)
+				require.Equal(t, `[1,2]`, string(env.Params))
+				env.Meta = map[string]interface{}{"key": "value"}
+				return nil
+			})
+			srv.Use(func(next Handler) Handler {
+				return func(call *Call) error {
+					meta = call.Meta
+					return next(call)
+				}
+			})
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`)
+			require.Nil(t, res.Error)
+			require.Equal(t, map[string]interface{}{"key": "value"}, meta)
+		})
+
+		t.Run("should rewrite MessagePack params", func(t *testing.T) {
+			var srv = NewRPC()
+
+			srv.AddHook(func(ctx context.Context, env *codec.Envelope) error {
+				params, err := msgpackv4.Marshal([]int{10, 20})
+				env.Params = params
+				return err
+			})
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			msg, err := msgpackv4.Marshal(map[string]interface{}{
+				"jsonrpc": "2.0", "id": 1, "method": "sum", "params": []int{1, 2},
+			})
+			require.NoError(t, err)
+
+			var out []byte
+			require.NoError(t, srv.ServeMessage(context.Background(), msgpack.NewCodec(codec.DefaultEncoderSelector), msg, func(b []byte) { out = b }))
+
+			res := make(map[string]interface{})
+			require.NoError(t, msgpackv4.Unmarshal(out, &res))
+			require.EqualValues(t, 30, res["result"])
+		})
+
+		t.Run("should rewrite CBOR params", func(t *testing.T) {
+			var srv = NewRPC()
+
+			srv.AddHook(func(ctx context.Context, env *codec.Envelope) error {
+				params, err := cborv2.Marshal([]int{10, 20})
+				env.Params = params
+				return err
+			})
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			msg, err := cborv2.Marshal(map[string]interface{}{
+				"jsonrpc": "2.0", "id": 1, "method": "sum", "params": []int{1, 2},
+			})
+			require.NoError(t, err)
+
+			var out []byte
+			require.NoError(t, srv.ServeMessage(context.Background(), cbor.NewCodec(codec.DefaultEncoderSelector), msg, func(b []byte) { out = b }))
+
+			res := make(map[string]interface{})
+			require.NoError(t, cborv2.Unmarshal(out, &res))
+			require.EqualValues(t, 30, res["result"])
+		})
+	})
+}
@@ -0,0 +1,278 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+)
+
+// invoker calls method with context or HTTP request, depending on what it
+// accepts, and arguments of the call.
+type invoker func(ctx context.Context, r *http.Request, args, reply interface{}) error
+
+// ErrCallType returned when middleware passes args or reply of types the
+// method doesn't accept.
+const ErrCallType = Error("call args or reply don't match method signature")
+
+// compile returns invoker of method fn, it's built once when method is
+// registered. Common signatures are called directly, others are called
+// through reflection. Missing args are passed as zero value.
+func compile(fn reflect.Value, withContext bool, argsType, replyType reflect.Type) invoker {
+	if inv := compileKnown(fn.Interface()); inv != nil {
+		return inv
+	}
+
+	zero := reflect.Zero(argsType)
+	return func(ctx context.Context, r *http.Request, args, reply interface{}) error {
+		var in [3]reflect.Value
+		if withContext {
+			in[0] = reflect.ValueOf(ctx)
+		} else {
+			in[0] = reflect.ValueOf(r)
+		}
+
+		// Args of interface type hold values of other types.
+		if in[1] = reflect.ValueOf(args); !in[1].IsValid() {
+			in[1] = zero
+		} else if !in[1].Type().AssignableTo(argsType) {
+			return ErrCallType
+		}
+		if in[2] = reflect.ValueOf(reply); !in[2].IsValid() || in[2].Type() != replyType {
+			return ErrCallType
+		}
+
+		// Cast the result to error if needed.
+		if err, ok := fn.Call(in[:])[0].Interface().(error); ok {
+			return err
+		}
+		return nil
+	}
+}
+
+// compileKnown returns invoker calling fn without reflection, nil is
+// returned when signature of fn isn't known.
+func compileKnown(fn interface{}) invoker {
+	switch fn := fn.(type) {
+	case func(context.Context, *struct{}, *interface{}) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			a, ok := structArgs(args)
+			res, ok2 := reply.(*interface{})
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(ctx, a, res)
+		}
+	case func(context.Context, *struct{}, *string) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			a, ok := structArgs(args)
+			res, ok2 := reply.(*string)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(ctx, a, res)
+		}
+	case func(context.Context, *struct{}, *int) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			a, ok := structArgs(args)
+			res, ok2 := reply.(*int)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(ctx, a, res)
+		}
+	case func(context.Context, *struct{}, *bool) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			a, ok := structArgs(args)
+			res, ok2 := reply.(*bool)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(ctx, a, res)
+		}
+	case func(context.Context, []interface{}, *interface{}) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			a, ok := listArgs(args)
+			res, ok2 := reply.(*interface{})
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(ctx, a, res)
+		}
+	case func(context.Context, []interface{}, *string) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			a, ok := listArgs(args)
+			res, ok2 := reply.(*string)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(ctx, a, res)
+		}
+	case func(context.Context, []interface{}, *int) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			a, ok := listArgs(args)
+			res, ok2 := reply.(*int)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(ctx, a, res)
+		}
+	case func(context.Context, []interface{}, *bool) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			a, ok := listArgs(args)
+			res, ok2 := reply.(*bool)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(ctx, a, res)
+		}
+	case func(context.Context, interface{}, *interface{}) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			res, ok := reply.(*interface{})
+			if !ok {
+				return ErrCallType
+			}
+			return fn(ctx, args, res)
+		}
+	case func(context.Context, interface{}, *string) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			res, ok := reply.(*string)
+			if !ok {
+				return ErrCallType
+			}
+			return fn(ctx, args, res)
+		}
+	case func(context.Context, interface{}, *int) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			res, ok := reply.(*int)
+			if !ok {
+				return ErrCallType
+			}
+			return fn(ctx, args, res)
+		}
+	case func(context.Context, interface{}, *bool) error:
+		return func(ctx context.Context, _ *http.Request, args, reply interface{}) error {
+			res, ok := reply.(*bool)
+			if !ok {
+				return ErrCallType
+			}
+			return fn(ctx, args, res)
+		}
+	case func(*http.Request, *struct{}, *interface{}) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			a, ok := structArgs(args)
+			res, ok2 := reply.(*interface{})
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(r, a, res)
+		}
+	case func(*http.Request, *struct{}, *string) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			a, ok := structArgs(args)
+			res, ok2 := reply.(*string)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(r, a, res)
+		}
+	case func(*http.Request, *struct{}, *int) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			a, ok := structArgs(args)
+			res, ok2 := reply.(*int)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(r, a, res)
+		}
+	case func(*http.Request, *struct{}, *bool) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			a, ok := structArgs(args)
+			res, ok2 := reply.(*bool)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(r, a, res)
+		}
+	case func(*http.Request, []interface{}, *interface{}) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			a, ok := listArgs(args)
+			res, ok2 := reply.(*interface{})
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(r, a, res)
+		}
+	case func(*http.Request, []interface{}, *string) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			a, ok := listArgs(args)
+			res, ok2 := reply.(*string)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(r, a, res)
+		}
+	case func(*http.Request, []interface{}, *int) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			a, ok := listArgs(args)
+			res, ok2 := reply.(*int)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(r, a, res)
+		}
+	case func(*http.Request, []interface{}, *bool) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			a, ok := listArgs(args)
+			res, ok2 := reply.(*bool)
+			if !ok || !ok2 {
+				return ErrCallType
+			}
+			return fn(r, a, res)
+		}
+	case func(*http.Request, interface{}, *interface{}) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			res, ok := reply.(*interface{})
+			if !ok {
+				return ErrCallType
+			}
+			return fn(r, args, res)
+		}
+	case func(*http.Request, interface{}, *string) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			res, ok := reply.(*string)
+			if !ok {
+				return ErrCallType
+			}
+			return fn(r, args, res)
+		}
+	case func(*http.Request, interface{}, *int) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			res, ok := reply.(*int)
+			if !ok {
+				return ErrCallType
+			}
+			return fn(r, args, res)
+		}
+	case func(*http.Request, interface{}, *bool) error:
+		return func(_ context.Context, r *http.Request, args, reply interface{}) error {
+			res, ok := reply.(*bool)
+			if !ok {
+				return ErrCallType
+			}
+			return fn(r, args, res)
+		}
+	}
+	return nil
+}
+
+// structArgs returns args of *struct{} type, missing args are nil.
+func structArgs(args interface{}) (*struct{}, bool) {
+	a, ok := args.(*struct{})
+	return a, ok || args == nil
+}
+
+// listArgs returns args of []interface{} type, missing args are nil.
+func listArgs(args interface{}) ([]interface{}, bool) {
+	a, ok := args.([]interface{})
+	return a, ok || args == nil
+}
@@ -0,0 +1,129 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type invokeArgs struct {
+	A, B int
+}
+
+func compileFunc(fn interface{}) invoker {
+	t := reflect.TypeOf(fn)
+	return compile(reflect.ValueOf(fn), t.In(0) == typeOfContext, t.In(1), t.In(2))
+}
+
+func TestInvokeSuite(t *testing.T) {
+	t.Run("Invoker test suite", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), invokeArgs{}, "value")
+
+		t.Run("should call method with named types", func(t *testing.T) {
+			fn := func(ctx context.Context, args *invokeArgs, reply *invokeArgs) error {
+				require.Nil(t, args)
+				reply.A = len(ctx.Value(invokeArgs{}).(string))
+				return nil
+			}
+
+			reply := new(invokeArgs)
+			require.NoError(t, compileFunc(fn)(ctx, nil, nil, reply))
+			require.Equal(t, 5, reply.A)
+
+			r := new(http.Request)
+			fail := func(req *http.Request, args []interface{}, reply *interface{}) error {
+				require.Equal(t, r, req)
+				require.Equal(t, []interface{}{1}, args)
+				return errors.New("fail")
+			}
+			require.EqualError(t, compileFunc(fail)(ctx, r, []interface{}{1}, new(interface{})), "fail")
+		})
+
+		t.Run("should call known signatures without allocations", func(t *testing.T) {
+			fn := func(ctx context.Context, args *struct{}, reply *int) error {
+				*reply++
+				return nil
+			}
+			require.NotNil(t, compileKnown(fn))
+
+			inv := compileFunc(fn)
+			reply := new(int)
+			require.Zero(t, testing.AllocsPerRun(100, func() {
+				_ = inv(ctx, nil, nil, reply)
+			}))
+			require.NotZero(t, *reply)
+
+			require.Equal(t, ErrCallType, inv(ctx, nil, &invokeArgs{}, reply))
+			require.Equal(t, ErrCallType, inv(ctx, nil, nil, new(string)))
+		})
+
+		t.Run("should accept args of interface type", func(t *testing.T) {
+			fn := func(r *http.Request, args interface{}, reply *[]interface{}) error {
+				*reply = append(*reply, args)
+				return nil
+			}
+			require.Nil(t, compileKnown(fn))
+
+			var reply []interface{}
+			require.NoError(t, compileFunc(fn)(ctx, new(http.Request), []int{1, 2}, &reply))
+			require.Equal(t, []interface{}{[]int{1, 2}}, reply)
+
+			srv := newTestRPC()
+			require.NoError(t, srv.AddMethod("wrap", fn))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "wrap", "params": [1, 2]}`)
+			require.Nil(t, res.Error)
+			require.Equal(t, `[[1,2]]`, string(res.Result))
+
+			reply = nil
+			require.NoError(t, srv.Call(ctx, "wrap", []int{1, 2}, &reply))
+			require.Len(t, reply, 1)
+		})
+
+		t.Run("should reject args and reply of other types", func(t *testing.T) {
+			var called bool
+			inv := compileFunc(func(ctx context.Context, args *invokeArgs, reply *int) error {
+				called = true
+				return nil
+			})
+
+			require.Equal(t, ErrCallType, inv(ctx, nil, invokeArgs{}, new(int)))
+			require.Equal(t, ErrCallType, inv(ctx, nil, &invokeArgs{}, new(string)))
+			require.Equal(t, ErrCallType, inv(ctx, nil, &invokeArgs{}, nil))
+			require.False(t, called)
+		})
+
+		t.Run("should pass args by value", func(t *testing.T) {
+			fn := func(ctx context.Context, args invokeArgs, reply *int) error {
+				if args.A < 0 {
+					return errors.New("negative")
+				}
+				*reply = args.A + args.B
+				return nil
+			}
+			inv := compileFunc(fn)
+
+			var reply int
+			require.NoError(t, inv(ctx, nil, invokeArgs{A: 1, B: 2}, &reply))
+			require.Equal(t, 3, reply)
+
+			require.NoError(t, inv(ctx, nil, nil, &reply))
+			require.Equal(t, 0, reply)
+
+			require.EqualError(t, inv(ctx, nil, invokeArgs{A: -1}, &reply), "negative")
+
+			r := new(http.Request)
+			inv = compileFunc(func(req *http.Request, args *invokeArgs, reply *int) error {
+				require.Equal(t, r, req)
+				*reply = args.A
+				return nil
+			})
+			require.NoError(t, inv(ctx, r, &invokeArgs{A: 5}, &reply))
+			require.Equal(t, 5, reply)
+		})
+	})
+}
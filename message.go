@@ -0,0 +1,23 @@
+package jsonrpc
+
+import (
+	"context"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+)
+
+// ServeMessage dispatches request or batch of requests decoded from msg by
+// codec, encoded responses are passed to send. Unlike ServeHTTP it doesn't
+// depend on transport the message was received with. Requests of a batch
+// are served one by one, ServeMessage returns when all of them are served.
+func (s *RPC) ServeMessage(ctx context.Context, cdc codec.MessageCodec, msg []byte, send func([]byte)) error {
+	reqs, err := cdc.NewMessage(msg, send)
+	if err != nil {
+		return err
+	}
+
+	for _, req := range reqs {
+		s.serve(ctx, req)
+	}
+	return nil
+}
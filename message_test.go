@@ -0,0 +1,60 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSuite(t *testing.T) {
+	t.Run("ServeMessage test suite", func(t *testing.T) {
+		t.Run("should serve batch without HTTP request", func(t *testing.T) {
+			var (
+				srv  = NewRPC()
+				sent [][]byte
+			)
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+			require.NoError(t, srv.AddMethod("ctx", func(ctx context.Context, args struct{}, reply *bool) error {
+				*reply = RequestFromContext(ctx) == nil
+				return nil
+			}))
+			require.NoError(t, srv.AddMethod("req", func(r *http.Request, args struct{}, reply *bool) error {
+				*reply = r != nil
+				return nil
+			}))
+
+			err := srv.ServeMessage(context.Background(), codec.NewCodec(), []byte(`[
+				{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]},
+				{"jsonrpc": "2.0", "id": 2, "method": "ctx"},
+				{"jsonrpc": "2.0", "id": 3, "method": "req"},
+				{"jsonrpc": "2.0", "id": 4, "method": "unknown"}
+			]`), func(msg []byte) { sent = append(sent, msg) })
+			require.NoError(t, err)
+			require.Len(t, sent, 1)
+
+			var res []testResponse
+			require.NoError(t, json.Unmarshal(sent[0], &res))
+			require.Len(t, res, 4)
+			require.Equal(t, `3`, string(res[0].Result))
+			require.Equal(t, `true`, string(res[1].Result))
+			require.Equal(t, `true`, string(res[2].Result))
+			require.Equal(t, codec.ErrNoMethod, res[3].Error.Code)
+		})
+
+		t.Run("should fail on bad message", func(t *testing.T) {
+			var (
+				srv  = NewRPC()
+				sent [][]byte
+			)
+
+			err := srv.ServeMessage(context.Background(), codec.NewCodec(), []byte(`invalid`),
+				func(msg []byte) { sent = append(sent, msg) })
+			require.Error(t, err)
+			require.Len(t, sent, 1)
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package jsonrpc
+
+import (
+	"context"
+)
+
+type (
+	// Call describes a single method invocation passed through middleware.
+	Call struct {
+		// Context of the call, it's passed to methods accepting
+		// context.Context. HTTP request the call came with can be
+		// obtained using RequestFromContext.
+		Context context.Context
+
+		// Method is the name of the invoked method.
+		Method string
+
+		// Meta holds data put into request envelope by hooks.
+		Meta map[string]interface{}
+
+		// Args holds decoded method arguments, replacing it changes
+		// the arguments passed to the method.
+		Args interface{}
+
+		// Reply is a pointer to the method reply.
+		Reply interface{}
+	}
+
+	// Handler invokes method described by Call.
+	Handler func(call *Call) error
+
+	// Middleware wraps Handler, it can be used to run cross-cutting logic
+	// (auth, logging, metrics) around method calls.
+	Middleware func(next Handler) Handler
+)
+
+// Use registers middleware applied to every method call. Middleware are
+// called in order they were registered, before method-specific ones.
+// Handlers of registered methods are wrapped again, so calls don't build
+// the chain.
+func (s *RPC) Use(mw ...Middleware) {
+	s.method.update(func(state *methodState) {
+		state.middleware = append(state.middleware, mw...)
+		for name, m := range state.items {
+			item := *m
+			item.wrapped = chain(item.handler, state.middleware)
+			state.items[name] = &item
+		}
+	})
+}
+
+// chain wraps handler with middleware, the first one becomes outermost.
+func chain(h Handler, mw []Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
@@ -0,0 +1,157 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/stretchr/testify/require"
+)
+
+type testResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *codec.Error    `json:"error"`
+}
+
+func newTestRPC() *RPC {
+	srv := NewRPC()
+	srv.AddCodec(codec.NewCodec(), misc.MIMEApplicationJSON)
+	return srv
+}
+
+func serveTestRequest(t *testing.T, srv *RPC, body string) *testResponse {
+	rec := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set(misc.HeaderContentType, misc.MIMEApplicationJSON)
+
+	require.NotPanics(t, func() { srv.ServeHTTP(rec, req) })
+
+	res := new(testResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+	return res
+}
+
+func sumMethod(r *http.Request, args []int, reply *int) error {
+	for i := range args {
+		*reply += args[i]
+	}
+	return nil
+}
+
+func TestMiddlewareSuite(t *testing.T) {
+	t.Run("Middleware test suite", func(t *testing.T) {
+		t.Run("should call middleware in order", func(t *testing.T) {
+			var (
+				srv   = newTestRPC()
+				order []string
+			)
+
+			mark := func(name string) Middleware {
+				return func(next Handler) Handler {
+					return func(call *Call) error {
+						order = append(order, name)
+						return next(call)
+					}
+				}
+			}
+
+			srv.Use(mark("first"), mark("second"))
+			require.NoError(t, srv.AddMethod("sum", sumMethod, mark("method")))
+			srv.Use(mark("third"))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`)
+			require.Nil(t, res.Error)
+			require.Equal(t, `3`, string(res.Result))
+			require.Equal(t, []string{"first", "second", "third", "method"}, order)
+		})
+
+		t.Run("should see method, args, reply and error", func(t *testing.T) {
+			var (
+				srv  = newTestRPC()
+				seen *Call
+				err  error
+			)
+
+			srv.Use(func(next Handler) Handler {
+				return func(call *Call) error {
+					err = next(call)
+					seen = call
+					return err
+				}
+			})
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`)
+			require.Nil(t, res.Error)
+			require.NoError(t, err)
+			require.NotNil(t, RequestFromContext(seen.Context))
+			require.Equal(t, "sum", seen.Method)
+			require.Equal(t, []int{1, 2}, seen.Args)
+			require.Equal(t, 3, *seen.Reply.(*int))
+		})
+
+		t.Run("should rewrite args", func(t *testing.T) {
+			var srv = newTestRPC()
+
+			require.NoError(t, srv.AddMethod("sum", sumMethod, func(next Handler) Handler {
+				return func(call *Call) error {
+					call.Args = append(call.Args.([]int), 10)
+					return next(call)
+				}
+			}))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`)
+			require.Nil(t, res.Error)
+			require.Equal(t, `13`, string(res.Result))
+		})
+
+		t.Run("should reject call", func(t *testing.T) {
+			var (
+				srv    = newTestRPC()
+				called bool
+			)
+
+			srv.Use(func(next Handler) Handler {
+				return func(call *Call) error {
+					return &codec.Error{Code: codec.ErrServer, Message: "forbidden"}
+				}
+			})
+			require.NoError(t, srv.AddMethod("sum", func(r *http.Request, args []int, reply *int) error {
+				called = true
+				return nil
+			}))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`)
+			require.False(t, called)
+			require.NotNil(t, res.Error)
+			require.Equal(t, codec.ErrServer, res.Error.Code)
+			require.Equal(t, "forbidden", res.Error.Message)
+		})
+
+		t.Run("should build chain once", func(t *testing.T) {
+			var (
+				srv   = newTestRPC()
+				built int
+			)
+
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+			srv.Use(func(next Handler) Handler {
+				built++
+				return next
+			})
+			require.NoError(t, srv.AddMethod("mul", sumMethod))
+			require.Equal(t, 2, built)
+
+			for i := 0; i < 3; i++ {
+				res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`)
+				require.Equal(t, `3`, string(res.Result))
+			}
+			require.Equal(t, 2, built)
+		})
+	})
+}
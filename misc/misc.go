@@ -18,14 +18,34 @@ const (
 	HeaderContentType = "Content-Type"
 	// HeaderAcceptEncoding constant
 	HeaderAcceptEncoding = "Accept-Encoding"
+	// HeaderAccept constant
+	HeaderAccept = "Accept"
 	// HeaderContentEncoding constant
 	HeaderContentEncoding = "Content-Encoding"
+	// HeaderContentLength constant
+	HeaderContentLength = "Content-Length"
+	// HeaderVary constant
+	HeaderVary = "Vary"
 	// MIMEApplicationJSON constant
 	MIMEApplicationJSON = "application/json"
 	// MIMEApplicationJSONCharsetUTF8 constant
 	MIMEApplicationJSONCharsetUTF8 = MIMEApplicationJSON + "; " + charsetUTF8
+	// MIMEApplicationCBOR constant
+	MIMEApplicationCBOR = "application/cbor"
+	// MIMEApplicationMsgpack constant
+	MIMEApplicationMsgpack = "application/msgpack"
+	// MIMEMultipartForm constant
+	MIMEMultipartForm = "multipart/form-data"
+	// MIMETextEventStream constant
+	MIMETextEventStream = "text/event-stream"
 	// HeaderXContentTypeOptions constant
 	HeaderXContentTypeOptions = "X-Content-Type-Options"
+	// HeaderETag constant
+	HeaderETag = "ETag"
+	// HeaderIfNoneMatch constant
+	HeaderIfNoneMatch = "If-None-Match"
+	// HeaderCacheControl constant
+	HeaderCacheControl = "Cache-Control"
 )
 
 // NewHTTPError creates a new HTTPError instance.
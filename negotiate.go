@@ -0,0 +1,119 @@
+package jsonrpc
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/misc"
+)
+
+type (
+	// negotiatedRequest reads request with codec picked by Content-Type and
+	// writes response with codec picked by Accept.
+	negotiatedRequest struct {
+		codec.Request
+		res codec.Responder
+	}
+
+	// mediaRange is a media range of Accept header.
+	mediaRange struct {
+		typ string
+		q   float64
+	}
+)
+
+// HandleError writes error response with response codec.
+func (r *negotiatedRequest) HandleError(err error) bool {
+	return r.res.HandleError(err)
+}
+
+// WriteResponse writes response with response codec.
+func (r *negotiatedRequest) WriteResponse(reply interface{}) {
+	r.res.WriteResponse(reply)
+}
+
+// WriteError writes error with response codec.
+func (r *negotiatedRequest) WriteError(status int, err error) {
+	r.res.WriteError(status, err)
+}
+
+// mediaType returns lowercased media type without parameters.
+func mediaType(v string) string {
+	if typ, _, err := mime.ParseMediaType(v); err == nil {
+		return typ
+	}
+	return strings.ToLower(strings.TrimSpace(strings.SplitN(v, ";", 2)[0]))
+}
+
+// lookup returns codec registered for media type, types with structured
+// syntax suffix, as in "application/vnd.api+json", fall back to codec of
+// "application/json".
+func (c *codecState) lookup(typ string) (codec.Interface, bool) {
+	if result, ok := c.items[typ]; ok {
+		return result, true
+	} else if i := strings.LastIndexByte(typ, '+'); i >= 0 {
+		result, ok = c.items["application/"+typ[i+1:]]
+		return result, ok
+	}
+	return nil, false
+}
+
+// responseCodec returns registered codec the client prefers with Accept
+// header, nil is returned when response should be written by request codec:
+// Accept is missing, it allows any type or request codec itself, or none of
+// its types can be written.
+func (s *RPC) responseCodec(r *http.Request, req codec.Interface) codec.ResponseCodec {
+	accept := r.Header.Get(misc.HeaderAccept)
+	if accept == "" {
+		return nil
+	}
+
+	state := s.codec.load()
+	for _, rng := range parseAccept(accept) {
+		if strings.HasSuffix(rng.typ, "/*") {
+			return nil
+		}
+
+		result, ok := state.lookup(rng.typ)
+		if !ok {
+			continue
+		} else if result == req {
+			return nil
+		} else if out, ok := result.(codec.ResponseCodec); ok {
+			return out
+		}
+	}
+	return nil
+}
+
+// parseAccept returns acceptable media ranges of Accept header ordered by
+// preference, malformed ones are skipped.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, item := range strings.Split(accept, ",") {
+		typ, params, err := mime.ParseMediaType(item)
+		if err != nil || !strings.Contains(typ, "/") {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, mediaRange{typ: typ, q: q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
@@ -0,0 +1,119 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/nspcc-dev/jsonrpc/codec/msgpack"
+	"github.com/nspcc-dev/jsonrpc/misc"
+	"github.com/stretchr/testify/require"
+	msgpackv4 "github.com/vmihailenco/msgpack/v4"
+)
+
+func newNegotiationServer(t *testing.T) *RPC {
+	srv := NewRPC()
+	srv.AddCodec(codec.NewCodec(), misc.MIMEApplicationJSON)
+	srv.AddCodec(msgpack.NewCodec(codec.DefaultEncoderSelector), misc.MIMEApplicationMsgpack)
+	require.NoError(t, srv.AddMethod("sum", func(r *http.Request, args *[]int, reply *int) error {
+		for _, v := range *args {
+			*reply += v
+		}
+		return nil
+	}))
+	return srv
+}
+
+func serveNegotiated(srv *RPC, contentType, accept string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1, 2]}`))
+	if contentType != "" {
+		req.Header.Set(misc.HeaderContentType, contentType)
+	}
+	if accept != "" {
+		req.Header.Set(misc.HeaderAccept, accept)
+	}
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNegotiationSuite(t *testing.T) {
+	t.Run("Content negotiation test suite", func(t *testing.T) {
+		t.Run("should match Content-Type", func(t *testing.T) {
+			srv := newNegotiationServer(t)
+
+			for _, contentType := range []string{
+				"application/json",
+				"Application/JSON",
+				"application/json; charset=UTF-8",
+				`application/json;charset="utf-8"`,
+				"application/vnd.api+json",
+			} {
+				res := new(testResponse)
+				require.NoError(t, json.Unmarshal(serveNegotiated(srv, contentType, "").Body.Bytes(), res), contentType)
+				require.Nil(t, res.Error, contentType)
+				require.Equal(t, `3`, string(res.Result), contentType)
+			}
+		})
+
+		t.Run("should reject bad Content-Type", func(t *testing.T) {
+			srv := newNegotiationServer(t)
+
+			for contentType, msg := range map[string]string{
+				"":                                 "code=415, message=rpc: unrecognized Content-Type: ",
+				"text/plain":                       "code=415, message=rpc: unrecognized Content-Type: text/plain",
+				"application/json; charset=latin1": "code=415, message=rpc: unsupported charset: latin1",
+				"application/json; =":              "code=400, message=rpc: malformed Content-Type: application/json; =",
+			} {
+				res := new(testResponse)
+				require.NoError(t, json.Unmarshal(serveNegotiated(srv, contentType, "").Body.Bytes(), res), contentType)
+				require.NotNil(t, res.Error, contentType)
+				require.Equal(t, msg, res.Error.Message, contentType)
+			}
+		})
+
+		t.Run("should use default codec", func(t *testing.T) {
+			srv := newNegotiationServer(t)
+			srv.SetDefaultCodec(misc.MIMEApplicationJSONCharsetUTF8)
+
+			res := new(testResponse)
+			require.NoError(t, json.Unmarshal(serveNegotiated(srv, "", "").Body.Bytes(), res))
+			require.Nil(t, res.Error)
+			require.Equal(t, `3`, string(res.Result))
+		})
+
+		t.Run("should pick response codec by Accept", func(t *testing.T) {
+			srv := newNegotiationServer(t)
+
+			rec := serveNegotiated(srv, misc.MIMEApplicationJSON, "application/json;q=0.5, application/msgpack")
+			require.Equal(t, misc.MIMEApplicationMsgpack, rec.Header().Get(misc.HeaderContentType))
+
+			res := make(map[string]interface{})
+			require.NoError(t, msgpackv4.Unmarshal(rec.Body.Bytes(), &res))
+			require.EqualValues(t, 1, res["id"])
+			require.EqualValues(t, 3, res["result"])
+
+			for _, accept := range []string{
+				"",
+				"*/*",
+				"application/*, application/msgpack",
+				"text/html, application/json",
+				"application/msgpack;q=0",
+			} {
+				rec = serveNegotiated(srv, misc.MIMEApplicationJSON, accept)
+				require.Equal(t, misc.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(misc.HeaderContentType), accept)
+			}
+		})
+
+		t.Run("should order Accept media ranges", func(t *testing.T) {
+			require.Equal(t, []mediaRange{
+				{typ: "application/cbor", q: 1},
+				{typ: "application/msgpack", q: 1},
+				{typ: "application/json", q: 0.5},
+			}, parseAccept("application/json;q=0.5, application/cbor, text/plain;q=0, bad;q=1, application/msgpack;q=1"))
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+)
+
+// Peer is the client side of persistent connection, handlers can use it to
+// call client methods.
+type Peer struct {
+	conn  *conn
+	mu    *sync.Mutex
+	last  uint64
+	calls map[uint64]chan codec.Response
+}
+
+const (
+	//ErrConnClosed when connection is closed before response is received
+	ErrConnClosed = Error("connection is closed")
+)
+
+// creates peer of the connection
+func newPeer(c *conn) *Peer {
+	return &Peer{
+		conn:  c,
+		mu:    new(sync.Mutex),
+		calls: make(map[uint64]chan codec.Response),
+	}
+}
+
+// PeerFromContext returns the client of persistent connection the call came
+// with, it's nil for other transports.
+func PeerFromContext(ctx context.Context) *Peer {
+	if in, ok := ctx.Value(inboundKey{}).(*inbound); ok {
+		return in.conn.peer
+	}
+	return nil
+}
+
+// Call calls client method and waits for the response, reply must be a
+// pointer, it can be nil when result isn't needed. Error sent by the client
+// is returned as *codec.Error.
+func (p *Peer) Call(ctx context.Context, method string, params, reply interface{}) error {
+	ch := make(chan codec.Response, 1)
+
+	p.mu.Lock()
+	p.last++
+	id := p.last
+	p.calls[id] = ch
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.calls, id)
+		p.mu.Unlock()
+	}()
+
+	msg, err := p.conn.cdc.NewCall(id, method, params)
+	if err != nil {
+		return err
+	}
+	p.conn.send(msg)
+
+	var res codec.Response
+	select {
+	case res = <-ch:
+	case <-p.conn.read:
+		// Response could be received before reading stopped.
+		select {
+		case res = <-ch:
+		default:
+			return ErrConnClosed
+		}
+	case <-ctx.Done():
+		// Handler context is done along with the connection one.
+		if p.conn.ctx.Err() != nil {
+			return ErrConnClosed
+		}
+		return ctx.Err()
+	case <-p.conn.ctx.Done():
+		return ErrConnClosed
+	}
+
+	if err = res.Err(); err != nil || reply == nil {
+		return err
+	}
+	return res.ReadResult(reply)
+}
+
+// Notify sends notification to the client.
+func (p *Peer) Notify(method string, params interface{}) error {
+	if p.conn.ctx.Err() != nil {
+		return ErrConnClosed
+	}
+
+	msg, err := p.conn.cdc.NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	p.conn.send(msg)
+	return nil
+}
+
+// resolve passes response to the waiting call, responses to unknown
+// requests are dropped.
+func (p *Peer) resolve(res codec.Response) {
+	p.mu.Lock()
+	ch, ok := p.calls[res.ID()]
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	// Duplicate responses are dropped as well.
+	select {
+	case ch <- res:
+	default:
+	}
+}
@@ -0,0 +1,232 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/stretchr/testify/require"
+)
+
+type testCall struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+func TestPeerSuite(t *testing.T) {
+	t.Run("Peer test suite", func(t *testing.T) {
+		t.Run("should call client method", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("transfer", func(ctx context.Context, args []string, reply *string) error {
+				return PeerFromContext(ctx).Call(ctx, "sign", args, reply)
+			}))
+
+			st, closer := serveTestStream(srv, ConnConfig{})
+			defer closer()
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "transfer", "params": ["tx"]}`)
+
+			call := new(testCall)
+			st.response(t, call)
+			require.Equal(t, "sign", call.Method)
+			require.Equal(t, `["tx"]`, string(call.Params))
+
+			// Unknown responses are dropped.
+			st.request(t, `{"jsonrpc": "2.0", "id": 100, "result": "unknown"}`)
+			st.request(t, `{"jsonrpc": "2.0", "id": "`+strconv.FormatUint(call.ID, 10)+`", "result": "signed"}`)
+
+			res := new(wsResponse)
+			st.response(t, res)
+			require.Equal(t, "1", res.ID.String())
+			require.Equal(t, `"signed"`, string(res.Result))
+		})
+
+		t.Run("should return client error", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("transfer", func(ctx context.Context, args []string, reply *string) error {
+				err := PeerFromContext(ctx).Call(ctx, "sign", args, reply)
+				require.IsType(t, (*codec.Error)(nil), err)
+				return err
+			}))
+
+			st, closer := serveTestStream(srv, ConnConfig{MaxInFlight: 1})
+			defer closer()
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "transfer", "params": ["tx"]}`)
+
+			call := new(testCall)
+			st.response(t, call)
+			st.request(t, `{"jsonrpc": "2.0", "id": `+strconv.FormatUint(call.ID, 10)+`, "error": {"code": 1, "message": "rejected"}}`)
+
+			res := new(wsResponse)
+			st.response(t, res)
+			require.Equal(t, "1", res.ID.String())
+			require.Equal(t, 1, res.Error.Code)
+			require.Equal(t, "rejected", res.Error.Message)
+		})
+
+		t.Run("should receive response while handlers wait", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("transfer", func(ctx context.Context, args []string, reply *string) error {
+				return PeerFromContext(ctx).Call(ctx, "sign", args, reply)
+			}))
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			st, closer := serveTestStream(srv, ConnConfig{MaxInFlight: 1})
+			defer closer()
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "transfer", "params": ["tx"]}`)
+
+			call := new(testCall)
+			st.response(t, call)
+
+			// Request waiting for the handler doesn't block the response.
+			st.request(t, `{"jsonrpc": "2.0", "id": 2, "method": "sum", "params": [1, 2]}`)
+			st.request(t, `{"jsonrpc": "2.0", "id": `+strconv.FormatUint(call.ID, 10)+`, "result": "signed"}`)
+
+			res := new(wsResponse)
+			st.response(t, res)
+			require.Equal(t, "1", res.ID.String())
+			require.Equal(t, `"signed"`, string(res.Result))
+
+			res = new(wsResponse)
+			st.response(t, res)
+			require.Equal(t, "2", res.ID.String())
+			require.Equal(t, `3`, string(res.Result))
+		})
+
+		t.Run("should send notification", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("ping", func(ctx context.Context, args struct{}, reply *bool) error {
+				*reply = true
+				return PeerFromContext(ctx).Notify("pong", nil)
+			}))
+
+			st, closer := serveTestStream(srv, ConnConfig{})
+			defer closer()
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "ping"}`)
+
+			call := new(testCall)
+			st.response(t, call)
+			require.Equal(t, "pong", call.Method)
+			require.EqualValues(t, 0, call.ID)
+
+			res := new(wsResponse)
+			st.response(t, res)
+			require.Equal(t, `true`, string(res.Result))
+		})
+
+		t.Run("should fail when connection is closed", func(t *testing.T) {
+			var (
+				srv    = NewRPC()
+				called = make(chan struct{})
+				result = make(chan error, 1)
+			)
+			require.NoError(t, srv.AddMethod("transfer", func(ctx context.Context, args []string, reply *string) error {
+				peer := PeerFromContext(ctx)
+				close(called)
+				result <- peer.Call(ctx, "sign", args, reply)
+				return nil
+			}))
+
+			st, closer := serveTestStream(srv, ConnConfig{})
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "transfer", "params": ["tx"]}`)
+			<-called
+			closer()
+
+			require.Equal(t, ErrConnClosed, <-result)
+		})
+
+		t.Run("should fail when client disconnects", func(t *testing.T) {
+			var (
+				srv    = NewRPC()
+				result = make(chan error, 1)
+			)
+			require.NoError(t, srv.AddMethod("transfer", func(ctx context.Context, args []string, reply *string) error {
+				result <- PeerFromContext(ctx).Call(ctx, "sign", args, reply)
+				return nil
+			}))
+
+			st, closer := serveTestStream(srv, ConnConfig{})
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "transfer", "params": ["tx"]}`)
+			st.response(t, new(testCall))
+
+			closed := make(chan struct{})
+			go func() {
+				closer()
+				close(closed)
+			}()
+
+			select {
+			case err := <-result:
+				require.Equal(t, ErrConnClosed, err)
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "call wasn't failed")
+			}
+			select {
+			case <-closed:
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "connection wasn't closed")
+			}
+		})
+
+		t.Run("should reject requests when queue is full", func(t *testing.T) {
+			var (
+				srv     = NewRPC()
+				release = make(chan struct{})
+				once    sync.Once
+			)
+			require.NoError(t, srv.AddMethod("wait", func(ctx context.Context, args struct{}, reply *bool) error {
+				<-release
+				*reply = true
+				return nil
+			}))
+
+			before := runtime.NumGoroutine()
+			st, closer := serveTestStream(srv, ConnConfig{MaxInFlight: 1, MaxQueued: 2})
+			defer closer()
+			defer once.Do(func() { close(release) })
+
+			for i := 1; i <= 20; i++ {
+				st.request(t, `{"jsonrpc": "2.0", "id": `+strconv.Itoa(i)+`, "method": "wait"}`)
+			}
+			// Connection goroutines and the queued messages.
+			require.True(t, runtime.NumGoroutine() <= before+5)
+
+			for i := 4; i <= 20; i++ {
+				res := new(wsResponse)
+				st.response(t, res)
+				require.Equal(t, strconv.Itoa(i), res.ID.String())
+				require.NotNil(t, res.Error)
+				require.Equal(t, ErrTooManyRequests.Error(), res.Error.Message)
+			}
+
+			once.Do(func() { close(release) })
+			for i := 1; i <= 3; i++ {
+				res := new(wsResponse)
+				st.response(t, res)
+				require.Equal(t, `true`, string(res.Result))
+			}
+		})
+
+		t.Run("should be nil without persistent connection", func(t *testing.T) {
+			var srv = newTestRPC()
+			require.NoError(t, srv.AddMethod("peer", func(ctx context.Context, args struct{}, reply *bool) error {
+				*reply = PeerFromContext(ctx) == nil
+				return nil
+			}))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "peer"}`)
+			require.Equal(t, `true`, string(res.Result))
+		})
+	})
+}
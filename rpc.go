@@ -1,10 +1,14 @@
 package jsonrpc
 
 import (
+	"context"
+	"io"
+	"mime"
 	"net/http"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode"
 	"unicode/utf8"
 
@@ -19,20 +23,44 @@ type (
 		method *methods
 	}
 
+	// codecs is copy-on-write registry, readers load its current state
+	// without locking, writers replace it.
 	codecs struct {
-		mu    *sync.RWMutex
+		mu    *sync.Mutex  // serializes writers
+		state atomic.Value // *codecState
+	}
+
+	// codecState is a snapshot of codec registry, it's not modified once
+	// stored.
+	codecState struct {
 		items map[string]codec.Interface
+		def   string // media type used when Content-Type is missing
+		limit int64  // limit of decompressed request body
 	}
 
+	// methods is copy-on-write registry, readers load its current state
+	// without locking, writers replace it.
 	methods struct {
-		mu    *sync.RWMutex
-		items map[string]*method
+		mu    *sync.Mutex  // serializes writers
+		state atomic.Value // *methodState
+	}
+
+	// methodState is a snapshot of method registry, it's not modified once
+	// stored.
+	methodState struct {
+		items      map[string]*method
+		middleware []Middleware // applied to every method
+		hooks      []Hook       // called before params are decoded
 	}
 
 	method struct {
-		method    reflect.Value // receiver method
-		argsType  reflect.Type  // type of the request argument
-		replyType reflect.Type  // type of the response argument
+		call      invoker      // receiver method compiled at registration
+		argsType  reflect.Type // type of the request argument
+		replyType reflect.Type // type of the response argument
+		context   bool         // first argument is context.Context
+		safe      bool         // method can be called with HTTP GET
+		handler   Handler      // invoker wrapped with method middleware
+		wrapped   Handler      // handler wrapped with global middleware
 	}
 
 	//Error is constant error
@@ -46,13 +74,13 @@ const (
 	//ErrNotAFunction when passed not a function
 	ErrNotAFunction = Error("method must be function")
 	//ErrNotEnoughArgs when passed less than three args
-	ErrNotEnoughArgs = Error("method needs three args: *http.Request, *args, *reply")
+	ErrNotEnoughArgs = Error("method needs three args: *http.Request or context.Context, *args, *reply")
 	//ErrNotEnoughOut when method has not output
 	ErrNotEnoughOut = Error("method needs one out: error")
 	//ErrNotReturnError when method out is not error
 	ErrNotReturnError = Error("method needs one out: error")
-	//ErrFirstArgRequest when first arg is not *http.Request
-	ErrFirstArgRequest = Error("method needs first parameter to be *http.Request")
+	//ErrFirstArgRequest when first arg is not *http.Request or context.Context
+	ErrFirstArgRequest = Error("method needs first parameter to be *http.Request or context.Context")
 	//ErrSecondArgError when 2nd arg is not pointer or not exported
 	ErrSecondArgError = Error("second argument must be a pointer and must be exported")
 	//ErrThirdArgError when 3rd arf is not pointer or not exported
@@ -60,27 +88,94 @@ const (
 )
 
 var (
-	// Precomputed the reflect.Type of error and http.Request
+	// Precomputed the reflect.Type of error, http.Request and context.Context
 	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
 	typeOfRequest = reflect.TypeOf((*http.Request)(nil)).Elem()
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
 )
 
+// ErrUnsafeMethod returned when method that isn't registered as safe is
+// called with HTTP GET.
+var ErrUnsafeMethod = &codec.Error{
+	Code:    codec.ErrInvalidRequest,
+	Message: "method can't be called with GET",
+}
+
 func (e Error) Error() string { return string(e) }
 
 // creates instance of codec registry
 func newCodecRegistry() *codecs {
-	return &codecs{
-		mu:    new(sync.RWMutex),
+	c := &codecs{mu: new(sync.Mutex)}
+	c.state.Store(&codecState{
 		items: make(map[string]codec.Interface),
+		limit: codec.DefaultDecompressedLimit,
+	})
+	return c
+}
+
+// load returns current state of codec registry, it must not be modified.
+func (c *codecs) load() *codecState {
+	return c.state.Load().(*codecState)
+}
+
+// update applies fn to a copy of current state and stores it.
+func (c *codecs) update(fn func(state *codecState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := c.load()
+	state := &codecState{
+		items: make(map[string]codec.Interface, len(old.items)+1),
+		def:   old.def,
+		limit: old.limit,
+	}
+	for k, v := range old.items {
+		state.items[k] = v
 	}
+	fn(state)
+	c.state.Store(state)
 }
 
 // creates instance of methid registry
 func newMethodRegistry() *methods {
-	return &methods{
-		mu:    new(sync.RWMutex),
+	m := &methods{mu: new(sync.Mutex)}
+	m.state.Store(&methodState{
 		items: make(map[string]*method),
+	})
+	return m
+}
+
+// load returns current state of method registry, it must not be modified.
+func (m *methods) load() *methodState {
+	return m.state.Load().(*methodState)
+}
+
+// update applies fn to a copy of current state and stores it, so readers
+// of the old one aren't affected.
+func (m *methods) update(fn func(state *methodState)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.load()
+	state := &methodState{
+		items:      make(map[string]*method, len(old.items)+1),
+		middleware: old.middleware[:len(old.middleware):len(old.middleware)],
+		hooks:      old.hooks[:len(old.hooks):len(old.hooks)],
+	}
+	for k, v := range old.items {
+		state.items[k] = v
 	}
+	fn(state)
+	m.state.Store(state)
+}
+
+// store registers method with the name, its handler is wrapped with global
+// middleware here, so it isn't done for every call.
+func (m *methods) store(name string, item *method) {
+	m.update(func(state *methodState) {
+		item.wrapped = chain(item.handler, state.middleware)
+		state.items[name] = item
+	})
 }
 
 // NewRPC create new server instance
@@ -91,29 +186,99 @@ func NewRPC() *RPC {
 	}
 }
 
-// AddCodec register codec
+// AddCodec register codec, media type parameters are ignored, so codec
+// registered for "application/json" serves "application/json; charset=utf-8"
+// as well.
 func (s *RPC) AddCodec(codec codec.Interface, mime string) {
-	s.codec.mu.Lock()
-	defer s.codec.mu.Unlock()
-	s.codec.items[strings.ToLower(mime)] = codec
+	typ := mediaType(mime)
+	s.codec.update(func(state *codecState) {
+		state.items[typ] = codec
+	})
+}
+
+// SetDefaultCodec sets media type of codec used for requests without
+// Content-Type. By default such requests are rejected, except for GET ones
+// which are decoded by JSON codec.
+func (s *RPC) SetDefaultCodec(mime string) {
+	typ := mediaType(mime)
+	s.codec.update(func(state *codecState) {
+		state.def = typ
+	})
+}
+
+// SetDecompressionLimit sets limit of decompressed body of requests sent
+// with Content-Encoding, codec.DefaultDecompressedLimit is used by default.
+func (s *RPC) SetDecompressionLimit(limit int64) {
+	s.codec.update(func(state *codecState) {
+		state.limit = limit
+	})
+}
+
+// returns limit of decompressed request body
+func (s *RPC) decompressionLimit() int64 {
+	return s.codec.load().limit
 }
 
 // try to get codec or return error
 func (s *RPC) getCodec(r *http.Request) (codec.Interface, error) {
-	mime := r.Header.Get(misc.HeaderContentType)
-	mime = strings.SplitAfterN(mime, ";", 1)[0]
+	state := s.codec.load()
+
+	contentType := r.Header.Get(misc.HeaderContentType)
+	if contentType == "" {
+		switch {
+		case state.def != "":
+			contentType = state.def
+		case r.Method == http.MethodGet:
+			// GET requests have no body, they are decoded by JSON codec.
+			contentType = misc.MIMEApplicationJSON
+		default:
+			return nil, misc.NewHTTPError(http.StatusUnsupportedMediaType, "rpc: unrecognized Content-Type: ")
+		}
+	}
 
-	s.codec.mu.RLock()
-	defer s.codec.mu.RUnlock()
-	if result, ok := s.codec.items[strings.ToLower(mime)]; ok {
+	typ, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, misc.NewHTTPError(http.StatusBadRequest, "rpc: malformed Content-Type: "+contentType)
+	} else if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return nil, misc.NewHTTPError(http.StatusUnsupportedMediaType, "rpc: unsupported charset: "+charset)
+	}
+
+	if result, ok := state.lookup(typ); ok {
 		return result, nil
 	}
-	return nil, misc.NewHTTPError(http.StatusUnsupportedMediaType, "rpc: unrecognized Content-Type: "+mime)
+	return nil, misc.NewHTTPError(http.StatusUnsupportedMediaType, "rpc: unrecognized Content-Type: "+typ)
 }
 
 // AddMethod register method
 // func(r *http.Request, args interface{}, reply *Reply) error
-func (s *RPC) AddMethod(name string, fn interface{}) error {
+// func(ctx context.Context, args interface{}, reply *Reply) error
+// Passed middleware are applied only to this method, after global ones.
+func (s *RPC) AddMethod(name string, fn interface{}, mw ...Middleware) error {
+	m, err := newMethod(fn, mw)
+	if err != nil {
+		return err
+	}
+
+	s.method.store(name, m)
+	return nil
+}
+
+// AddSafeMethod registers method the same way AddMethod does and marks it
+// safe, i.e. it has no side effects and its result can be cached. Only safe
+// methods can be called with HTTP GET, see codec.WithGET.
+func (s *RPC) AddSafeMethod(name string, fn interface{}, mw ...Middleware) error {
+	m, err := newMethod(fn, mw)
+	if err != nil {
+		return err
+	}
+
+	m.safe = true
+	s.method.store(name, m)
+	return nil
+}
+
+// newMethod validates signature of fn and compiles its invoker.
+func newMethod(fn interface{}, mw []Middleware) (*method, error) {
 	var (
 		v     = reflect.ValueOf(fn)
 		t     = reflect.TypeOf(fn)
@@ -122,48 +287,52 @@ func (s *RPC) AddMethod(name string, fn interface{}) error {
 	)
 
 	if v.Kind() != reflect.Func {
-		return ErrNotAFunction
+		return nil, ErrNotAFunction
 	} else if t.NumIn() != 3 {
-		return ErrNotEnoughArgs
+		return nil, ErrNotEnoughArgs
 	} else if t.NumOut() != 1 {
-		return ErrNotEnoughOut
+		return nil, ErrNotEnoughOut
 	}
 
 	// Method must return error
 	if rt := t.Out(0); rt != typeOfError {
-		return ErrNotReturnError
+		return nil, ErrNotReturnError
 	}
 
-	// First argument must be *http.Request
-	if rt := t.In(0); rt.Kind() != reflect.Ptr || rt.Elem() != typeOfRequest {
-		return ErrFirstArgRequest
+	// First argument must be *http.Request or context.Context
+	rt := t.In(0)
+	withContext := rt == typeOfContext
+	if !withContext && (rt.Kind() != reflect.Ptr || rt.Elem() != typeOfRequest) {
+		return nil, ErrFirstArgRequest
 	}
 
 	// Second argument must be exported or builtin.
 	if args = t.In(1); !isExportedOrBuiltin(args) {
-		return ErrSecondArgError
+		return nil, ErrSecondArgError
 	}
 	// Third argument must be a pointer and must be exported or builtin.
 	if reply = t.In(2); !validateInputType(reply) {
-		return ErrThirdArgError
+		return nil, ErrThirdArgError
 	}
 
-	s.method.mu.Lock()
-	s.method.items[name] = &method{
+	m := &method{
+		call:      compile(v, withContext, args, reply),
 		argsType:  args,
 		replyType: reply.Elem(),
-		method:    v,
+		context:   withContext,
 	}
-	s.method.mu.Unlock()
-
-	return nil
+	m.handler = chain(m.invoke, mw)
+	return m, nil
 }
 
 // try to find and return method
 func (s *RPC) get(name string) (*method, error) {
-	s.method.mu.RLock()
-	defer s.method.mu.RUnlock()
-	if caller, ok := s.method.items[name]; ok {
+	return s.method.load().get(name)
+}
+
+// get returns method of the registry state or error when it's not found.
+func (state *methodState) get(name string) (*method, error) {
+	if caller, ok := state.items[name]; ok {
 		return caller, nil
 	}
 	return nil, &codec.Error{
@@ -172,26 +341,55 @@ func (s *RPC) get(name string) (*method, error) {
 	}
 }
 
-// ServeHTTP implementation of http.Handler
+// ServeHTTP implementation of http.Handler, it's HTTP adapter on top of
+// transport-agnostic dispatching.
 func (s *RPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var (
-		err    error
-		cdc    codec.Interface
-		req    codec.Request
-		caller *method
+		err error
+		cdc codec.Interface
+		req codec.Request
 	)
 
 	enc := new(CompressionSelector).Select(r)
 	res := codec.NewEncodedResponse(w, enc)
+	defer func() { _ = codec.CloseEncoded(res) }()
 
 	if cdc, err = s.getCodec(r); err != nil {
 		codec.WriteError(res, err)
 		return
+	} else if err = codec.DecompressBody(r, s.decompressionLimit()); err != nil {
+		codec.WriteError(res, err)
+		return
 	} else if req, err = cdc.NewRequest(w, r); err != nil {
 		codec.WriteError(res, err)
 		return
 	}
 
+	if c, ok := req.(io.Closer); ok {
+		defer func() { _ = c.Close() }()
+	}
+
+	// Event stream carries the response in request codec's format, so
+	// streamed requests aren't negotiated.
+	if out := s.responseCodec(r, cdc); out != nil && !streaming(req) {
+		// Request is answered by its own codec when id can't be encoded.
+		if res, err := out.NewResponder(w, r, req.Envelope()); err == nil {
+			req = &negotiatedRequest{Request: req, res: res}
+		}
+	}
+
+	s.serve(withRequest(r.Context(), r), req)
+}
+
+// serve dispatches decoded request to the registered method and writes
+// the response, it doesn't depend on transport the request came with.
+func (s *RPC) serve(ctx context.Context, req codec.Request) {
+	var (
+		err    error
+		caller *method
+		state  = s.method.load()
+	)
+
 	defer func() { // catch internal errors:
 		if err := recover(); err != nil {
 			req.HandleError(&codec.Error{
@@ -202,8 +400,19 @@ func (s *RPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// Hooks can replace the envelope, so it's checked before they run.
+	safe := req.Envelope().Safe()
+
+	// Run hooks on parsed envelope
+	if err = state.runHooks(ctx, req.Envelope()); req.HandleError(err) {
+		return
+	}
+
 	// Get method or return error
-	if caller, err = s.get(req.Method()); req.HandleError(err) {
+	if caller, err = state.get(req.Method()); req.HandleError(err) {
+		return
+	} else if safe && !caller.safe {
+		req.HandleError(ErrUnsafeMethod)
 		return
 	}
 
@@ -213,20 +422,32 @@ func (s *RPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Call the service method.
-	reply := reflect.New(caller.replyType)
-	errValue := caller.method.Call([]reflect.Value{
-		reflect.ValueOf(r),
-		args.Elem(),
-		reply,
-	})
+	call := &Call{
+		Context: withEventStream(ctx, req),
+		Method:  req.Method(),
+		Meta:    req.Envelope().Meta,
+		Args:    args.Elem().Interface(),
+		Reply:   reflect.New(caller.replyType).Interface(),
+	}
 
-	// Cast the result to error if needed.
-	if errValue[0].Interface() != nil && req.HandleError(errValue[0].Interface().(error)) {
+	// Call the service method through middleware.
+	if err = caller.wrapped(call); req.HandleError(err) {
 		return
 	}
 
-	req.WriteResponse(reply.Interface())
+	req.WriteResponse(call.Reply)
+}
+
+// invoke calls the receiver method with arguments of the call.
+func (m *method) invoke(call *Call) error {
+	var r *http.Request
+	if !m.context {
+		// Call may come without HTTP request.
+		if r = RequestFromContext(call.Context); r == nil {
+			r = stubRequest(call.Context)
+		}
+	}
+	return m.call(call.Context, r, call.Args, call.Reply)
 }
 
 // isExported returns true of a string is an exported (upper case) name.
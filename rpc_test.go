@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"compress/flate"
 	"compress/gzip"
+	"encoding/json"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/nspcc-dev/jsonrpc/codec"
@@ -371,6 +375,127 @@ func TestRPCSuite(t *testing.T) {
 			require.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"code=415, message=rpc: unrecognized Content-Type: "}}`, body)
 		})
 
+		t.Run("should serve multipart request", func(t *testing.T) {
+			var (
+				rec  = httptest.NewRecorder()
+				srv  = NewRPC()
+				body = new(bytes.Buffer)
+				mw   = multipart.NewWriter(body)
+			)
+			srv.AddCodec(codec.NewMultipart(codec.DefaultEncoderSelector, 0), misc.MIMEMultipartForm)
+
+			err := srv.AddMethod("size", func(r *http.Request, args []codec.Blob, reply *int) error {
+				data, err := ioutil.ReadAll(&args[0])
+				*reply = len(data)
+				return err
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, mw.WriteField(codec.MultipartRequestPart, `{"jsonrpc": "2.0", "id": 1, "method": "size", "params": ["file"]}`))
+			fw, err := mw.CreateFormFile("file", "file.bin")
+			require.NoError(t, err)
+			_, err = fw.Write([]byte("binary"))
+			require.NoError(t, err)
+			require.NoError(t, mw.Close())
+
+			req, err := http.NewRequest(http.MethodPost, "/", body)
+			require.NoError(t, err)
+			req.Header.Set(misc.HeaderContentType, mw.FormDataContentType())
+
+			require.NotPanics(t, func() { srv.ServeHTTP(rec, req) })
+
+			res := new(testResponse)
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+			require.Nil(t, res.Error)
+			require.Equal(t, `6`, string(res.Result))
+		})
+
+		t.Run("should serve compressed request", func(t *testing.T) {
+			srv := NewRPC()
+			srv.AddCodec(codec.NewCodec(), misc.MIMEApplicationJSON)
+			require.NoError(t, srv.AddMethod("echo", func(r *http.Request, args *string, reply *string) error {
+				*reply = *args
+				return nil
+			}))
+
+			buf := new(bytes.Buffer)
+			gz := gzip.NewWriter(buf)
+			_, err := gz.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "echo", "params": "` + strings.Repeat("a", 1000) + `"}`))
+			require.NoError(t, err)
+			require.NoError(t, gz.Close())
+
+			serve := func() (*httptest.ResponseRecorder, *testResponse) {
+				rec := httptest.NewRecorder()
+				req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(buf.Bytes()))
+				require.NoError(t, err)
+				req.Header.Set(misc.HeaderContentType, misc.MIMEApplicationJSON)
+				req.Header.Set(misc.HeaderContentEncoding, "gzip")
+				srv.ServeHTTP(rec, req)
+
+				res := new(testResponse)
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+				return rec, res
+			}
+
+			rec, res := serve()
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Nil(t, res.Error)
+			require.Len(t, res.Result, 1002)
+
+			srv.SetDecompressionLimit(100)
+			rec, res = serve()
+			require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+			require.NotNil(t, res.Error)
+			require.Equal(t, codec.ErrBodyTooLarge.Error(), res.Error.Message)
+		})
+
+		t.Run("should register methods while serving", func(t *testing.T) {
+			srv := NewRPC()
+			srv.AddCodec(codec.NewCodec(), misc.MIMEApplicationJSON)
+			require.NoError(t, srv.AddMethod("echo", func(r *http.Request, args *string, reply *string) error {
+				*reply = *args
+				return nil
+			}))
+
+			methods := srv.method.load().items
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						rec := httptest.NewRecorder()
+						req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "echo", "params": "a"}`))
+						if err != nil {
+							panic(err)
+						}
+						req.Header.Set(misc.HeaderContentType, misc.MIMEApplicationJSON)
+						srv.ServeHTTP(rec, req)
+						if !strings.Contains(rec.Body.String(), `"result":"a"`) {
+							panic(rec.Body.String())
+						}
+					}
+				}()
+			}
+
+			for i := 0; i < 100; i++ {
+				require.NoError(t, srv.AddSafeMethod("echo"+strconv.Itoa(i), func(r *http.Request, args *string, reply *string) error {
+					return nil
+				}))
+				srv.AddCodec(codec.NewCodec(), "application/json-"+strconv.Itoa(i))
+			}
+			wg.Wait()
+
+			// Stored snapshots aren't modified by writers.
+			require.Len(t, methods, 1)
+			require.Len(t, srv.method.load().items, 101)
+
+			m, err := srv.get("echo99")
+			require.NoError(t, err)
+			require.True(t, m.safe)
+		})
+
 		t.Run("should fail on bad method", func(t *testing.T) {
 			t.Run("method must be function", func(t *testing.T) {
 				var srv = NewRPC()
@@ -0,0 +1,111 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/textproto"
+	"os"
+	"strconv"
+)
+
+type (
+	// Framing of messages sent over byte stream.
+	Framing int
+
+	// headerStream frames messages with Content-Length header as Language
+	// Server Protocol does.
+	headerStream struct {
+		rwc   io.ReadWriteCloser
+		r     *textproto.Reader
+		limit int64
+	}
+
+	// stdio joins standard input and output into single connection.
+	stdio struct {
+		io.Reader
+		io.Writer
+	}
+)
+
+const (
+	// FramingHeader prefixes every message with "Content-Length: <size>"
+	// header followed by an empty line, other headers are ignored.
+	FramingHeader Framing = iota
+	// FramingNDJSON terminates every message with newline.
+	FramingNDJSON
+)
+
+const (
+	//ErrBadFraming when message header is malformed
+	ErrBadFraming = Error("bad message framing")
+)
+
+// ServeStdio serves messages of standard input and writes responses to
+// standard output, see ServeStream. It returns when input is closed.
+func (s *RPC) ServeStdio(ctx context.Context, framing Framing, cfg ConnConfig) {
+	s.ServeStream(ctx, &stdio{Reader: os.Stdin, Writer: os.Stdout}, framing, cfg)
+}
+
+// ServeStream serves messages of the connection framed as specified until
+// it's closed. Messages are served concurrently, responses are written in
+// completion order.
+func (s *RPC) ServeStream(ctx context.Context, rwc io.ReadWriteCloser, framing Framing, cfg ConnConfig) {
+	cfg = cfg.withDefaults()
+	s.newConn(ctx, newFramedStream(rwc, framing, cfg.ReadLimit), cfg).serve()
+}
+
+// newFramedStream returns stream of messages framed as specified.
+func newFramedStream(rwc io.ReadWriteCloser, framing Framing, limit int64) stream {
+	if framing == FramingNDJSON {
+		return newLineStream(rwc, limit)
+	}
+	return &headerStream{
+		rwc:   rwc,
+		r:     textproto.NewReader(bufio.NewReader(rwc)),
+		limit: limit,
+	}
+}
+
+// ReadMessage reads headers and the message of the specified length.
+func (s *headerStream) ReadMessage() ([]byte, error) {
+	header, err := s.r.ReadMIMEHeader()
+	if err == io.EOF && len(header) == 0 {
+		return nil, err
+	} else if err != nil {
+		return nil, ErrBadFraming
+	}
+
+	size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return nil, ErrBadFraming
+	} else if size > s.limit {
+		return nil, ErrMessageTooLarge
+	}
+
+	msg := make([]byte, size)
+	if _, err = io.ReadFull(s.r.R, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// WriteMessage writes Content-Length header followed by the message.
+func (s *headerStream) WriteMessage(msg []byte) error {
+	buf := make([]byte, 0, len(msg)+32)
+	buf = append(buf, "Content-Length: "...)
+	buf = strconv.AppendInt(buf, int64(len(msg)), 10)
+	buf = append(buf, "\r\n\r\n"...)
+	_, err := s.rwc.Write(append(buf, msg...))
+	return err
+}
+
+// Close closes the underlying connection.
+func (s *headerStream) Close() error {
+	return s.rwc.Close()
+}
+
+// Close closes standard input, so the pending read is interrupted.
+func (s *stdio) Close() error {
+	return os.Stdin.Close()
+}
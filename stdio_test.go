@@ -0,0 +1,108 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStdio(srv *RPC, framing Framing, cfg ConnConfig) (net.Conn, func()) {
+	var (
+		client, server = net.Pipe()
+		done           = make(chan struct{})
+	)
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	go func() {
+		srv.ServeStream(context.Background(), server, framing, cfg)
+		close(done)
+	}()
+	return client, func() {
+		_ = client.Close()
+		<-done
+	}
+}
+
+func writeFramed(t *testing.T, w io.Writer, msg string) {
+	_, err := w.Write([]byte("Content-Length: " + strconv.Itoa(len(msg)) + "\r\n" +
+		"Content-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n" + msg))
+	require.NoError(t, err)
+}
+
+func readFramed(t *testing.T, r *textproto.Reader, res interface{}) {
+	header, err := r.ReadMIMEHeader()
+	require.NoError(t, err)
+
+	size, err := strconv.Atoi(header.Get("Content-Length"))
+	require.NoError(t, err)
+
+	msg := make([]byte, size)
+	_, err = io.ReadFull(r.R, msg)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(msg, res))
+}
+
+func TestStdioSuite(t *testing.T) {
+	t.Run("Stdio test suite", func(t *testing.T) {
+		t.Run("should serve messages with Content-Length header", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			nc, closer := newTestStdio(srv, FramingHeader, ConnConfig{})
+			defer closer()
+
+			r := textproto.NewReader(bufio.NewReader(nc))
+			for i := 1; i <= 2; i++ {
+				go writeFramed(t, nc, `{"jsonrpc": "2.0", "id": `+strconv.Itoa(i)+`, "method": "sum", "params": [1,2]}`)
+
+				res := new(wsResponse)
+				readFramed(t, r, res)
+				require.Equal(t, strconv.Itoa(i), res.ID.String())
+				require.Equal(t, `3`, string(res.Result))
+			}
+		})
+
+		t.Run("should serve newline-delimited messages", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			nc, closer := newTestStdio(srv, FramingNDJSON, ConnConfig{})
+			defer closer()
+
+			go func() {
+				_, _ = nc.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}` + "\n"))
+			}()
+
+			sc := bufio.NewScanner(nc)
+			res := new(wsResponse)
+			readTCPResponse(t, sc, res)
+			require.Equal(t, `3`, string(res.Result))
+		})
+
+		t.Run("should close connection on bad framing", func(t *testing.T) {
+			for _, msg := range []string{
+				"Content-Length: abc\r\n\r\n{}",
+				"Content-Type: application/json\r\n\r\n{}",
+				"Content-Length: 4096\r\n\r\n{}",
+			} {
+				var srv = NewRPC()
+
+				nc, closer := newTestStdio(srv, FramingHeader, ConnConfig{ReadLimit: 16})
+
+				go func() { _, _ = nc.Write([]byte(msg)) }()
+
+				_, err := nc.Read(make([]byte, 1))
+				require.Equal(t, io.EOF, err, msg)
+				closer()
+			}
+		})
+	})
+}
@@ -0,0 +1,284 @@
+package jsonrpc
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/nspcc-dev/jsonrpc/codec"
+)
+
+type (
+	// OverflowPolicy defines what happens to notifications that don't fit
+	// into subscription buffer.
+	OverflowPolicy int
+
+	// SubscriptionConfig configures notifications delivery.
+	SubscriptionConfig struct {
+		// Buffer is the number of notifications waiting to be sent.
+		Buffer int
+
+		// Overflow is applied when buffer is full.
+		Overflow OverflowPolicy
+	}
+
+	// SubscriptionResult is the params of subscription notification.
+	SubscriptionResult struct {
+		Subscription string      `json:"subscription"`
+		Result       interface{} `json:"result"`
+	}
+
+	// subscriber creates subscriptions using registered function.
+	subscriber struct {
+		fn       reflect.Value
+		argsType reflect.Type
+		cfg      SubscriptionConfig
+	}
+
+	// subscription pushes items received from the channel as notifications.
+	subscription struct {
+		id     string
+		conn   *conn
+		cfg    SubscriptionConfig
+		source reflect.Value
+		queue  chan []byte
+		ctx    context.Context
+		cancel context.CancelFunc
+	}
+
+	// subscriptions is the registry of connection subscriptions.
+	subscriptions struct {
+		mu    *sync.Mutex
+		last  uint64
+		items map[string]*subscription
+	}
+
+	// inbound is a message served within the connection, subscriptions
+	// created by its requests start after responses are sent.
+	inbound struct {
+		mu   *sync.Mutex
+		conn *conn
+		subs []*subscription
+	}
+
+	// inboundKey is the context key of inbound message.
+	inboundKey struct{}
+)
+
+const (
+	// OverflowDrop drops notifications that don't fit into the buffer.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowDisconnect closes the connection of a slow client.
+	OverflowDisconnect
+)
+
+const (
+	// SubscriptionMethod is the method of notifications pushed to subscribers.
+	SubscriptionMethod = "subscription"
+	// UnsubscribeMethod is the method that cancels subscriptions.
+	UnsubscribeMethod = "unsubscribe"
+	// DefaultSubscriptionBuffer is used when SubscriptionConfig.Buffer isn't set.
+	DefaultSubscriptionBuffer = 64
+)
+
+const (
+	//ErrSubscriptionSignature when subscription function has wrong signature
+	ErrSubscriptionSignature = Error("subscription must be func(context.Context, args) (<-chan T, error)")
+)
+
+// ErrNoConnection returned when subscription is requested without persistent
+// connection.
+var ErrNoConnection = &codec.Error{
+	Code:    codec.ErrServer,
+	Message: "subscriptions require persistent connection",
+}
+
+// creates instance of subscription registry
+func newSubscriptionRegistry() *subscriptions {
+	return &subscriptions{
+		mu:    new(sync.Mutex),
+		items: make(map[string]*subscription),
+	}
+}
+
+// AddSubscription registers subscription, it's called as a regular method
+// and replies with subscription ID. Items received from returned channel are
+// pushed to the client as SubscriptionMethod notifications until the client
+// calls UnsubscribeMethod, disconnects or the channel is closed. Context
+// passed to fn is done when subscription ends, producer must stop sending
+// then.
+// func(ctx context.Context, args interface{}) (<-chan T, error)
+// Subscriptions are available only with persistent connection transports.
+func (s *RPC) AddSubscription(name string, fn interface{}, cfg SubscriptionConfig, mw ...Middleware) error {
+	var (
+		v = reflect.ValueOf(fn)
+		t = reflect.TypeOf(fn)
+	)
+
+	if v.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 2 ||
+		t.In(0) != typeOfContext || !isExportedOrBuiltin(t.In(1)) ||
+		t.Out(0).Kind() != reflect.Chan || t.Out(0).ChanDir()&reflect.RecvDir == 0 ||
+		t.Out(1) != typeOfError {
+		return ErrSubscriptionSignature
+	}
+
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = DefaultSubscriptionBuffer
+	}
+
+	sub := &subscriber{
+		fn:       v,
+		argsType: t.In(1),
+		cfg:      cfg,
+	}
+
+	m := &method{
+		argsType:  sub.argsType,
+		replyType: reflect.TypeOf(""),
+		context:   true,
+	}
+	m.handler = chain(sub.invoke, mw)
+
+	s.method.store(name, m)
+
+	// Unsubscribe is registered along with the first subscription.
+	if _, err := s.get(UnsubscribeMethod); err != nil {
+		return s.AddMethod(UnsubscribeMethod, unsubscribe)
+	}
+	return nil
+}
+
+// invoke creates subscription and replies with its ID.
+func (sub *subscriber) invoke(call *Call) error {
+	in, ok := call.Context.Value(inboundKey{}).(*inbound)
+	if !ok {
+		return ErrNoConnection
+	}
+
+	args := reflect.ValueOf(call.Args)
+	if !args.IsValid() {
+		args = reflect.Zero(sub.argsType)
+	}
+
+	ctx, cancel := context.WithCancel(call.Context)
+	out := sub.fn.Call([]reflect.Value{reflect.ValueOf(ctx), args})
+	if err, ok := out[1].Interface().(error); ok {
+		cancel()
+		return err
+	}
+
+	subs := in.conn.subs
+	subs.mu.Lock()
+	subs.last++
+	s := &subscription{
+		id:     strconv.FormatUint(subs.last, 10),
+		conn:   in.conn,
+		cfg:    sub.cfg,
+		source: out[0],
+		queue:  make(chan []byte, sub.cfg.Buffer),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	subs.items[s.id] = s
+	subs.mu.Unlock()
+
+	in.mu.Lock()
+	in.subs = append(in.subs, s)
+	in.mu.Unlock()
+
+	*call.Reply.(*string) = s.id
+	return nil
+}
+
+// unsubscribe cancels subscriptions of the connection, it replies true if
+// all of them existed.
+func unsubscribe(ctx context.Context, ids []string, reply *bool) error {
+	in, ok := ctx.Value(inboundKey{}).(*inbound)
+	if !ok {
+		return ErrNoConnection
+	}
+
+	*reply = true
+	for _, id := range ids {
+		in.conn.subs.mu.Lock()
+		s, ok := in.conn.subs.items[id]
+		in.conn.subs.mu.Unlock()
+
+		if !ok {
+			*reply = false
+			continue
+		}
+		s.stop()
+	}
+	return nil
+}
+
+// start starts subscriptions created while serving the message.
+func (in *inbound) start() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for _, s := range in.subs {
+		go s.run()
+		go s.writeLoop()
+	}
+}
+
+// run receives items from the source and queues notifications.
+func (s *subscription) run() {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: s.source},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.ctx.Done())},
+	}
+
+	for {
+		chosen, item, ok := reflect.Select(cases)
+		if chosen != 0 {
+			return
+		} else if !ok { // source is closed, send what's left
+			close(s.queue)
+			return
+		}
+
+		msg, err := s.conn.cdc.NewNotification(SubscriptionMethod, &SubscriptionResult{
+			Subscription: s.id,
+			Result:       item.Interface(),
+		})
+		if err != nil {
+			continue
+		}
+
+		select {
+		case s.queue <- msg:
+		default:
+			if s.cfg.Overflow == OverflowDisconnect {
+				s.conn.close()
+				return
+			}
+		}
+	}
+}
+
+// writeLoop passes queued notifications to the connection.
+func (s *subscription) writeLoop() {
+	defer s.stop()
+	for {
+		select {
+		case msg, ok := <-s.queue:
+			if !ok {
+				return
+			}
+			s.conn.send(msg)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// stop cancels the subscription and removes it from the registry.
+func (s *subscription) stop() {
+	s.cancel()
+	s.conn.subs.mu.Lock()
+	delete(s.conn.subs.items, s.id)
+	s.conn.subs.mu.Unlock()
+}
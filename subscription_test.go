@@ -0,0 +1,256 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// testStream is in-memory stream, writes block until they are read.
+	testStream struct {
+		in     chan []byte
+		out    chan []byte
+		once   sync.Once
+		closed chan struct{}
+	}
+
+	testNotification struct {
+		Method string `json:"method"`
+		Params struct {
+			Subscription string          `json:"subscription"`
+			Result       json.RawMessage `json:"result"`
+		} `json:"params"`
+	}
+)
+
+func newTestStream() *testStream {
+	return &testStream{
+		in:     make(chan []byte),
+		out:    make(chan []byte),
+		closed: make(chan struct{}),
+	}
+}
+
+func (s *testStream) ReadMessage() ([]byte, error) {
+	select {
+	case msg := <-s.in:
+		return msg, nil
+	case <-s.closed:
+		return nil, io.EOF
+	}
+}
+
+func (s *testStream) WriteMessage(msg []byte) error {
+	select {
+	case s.out <- msg:
+		return nil
+	case <-s.closed:
+		return io.ErrClosedPipe
+	}
+}
+
+func (s *testStream) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func (s *testStream) request(t *testing.T, msg string) {
+	select {
+	case s.in <- []byte(msg):
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "request wasn't read")
+	}
+}
+
+func (s *testStream) response(t *testing.T, res interface{}) {
+	select {
+	case msg := <-s.out:
+		require.NoError(t, json.Unmarshal(msg, res))
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "response wasn't written")
+	}
+}
+
+// serveTestStream serves the stream until test is done.
+func serveTestStream(srv *RPC, cfg ConnConfig) (*testStream, func()) {
+	var (
+		st   = newTestStream()
+		done = make(chan struct{})
+	)
+	go func() {
+		srv.newConn(context.Background(), st, cfg).serve()
+		close(done)
+	}()
+	return st, func() {
+		_ = st.Close()
+		<-done
+	}
+}
+
+func TestSubscriptionSuite(t *testing.T) {
+	t.Run("Subscription test suite", func(t *testing.T) {
+		t.Run("should push notifications until unsubscribe", func(t *testing.T) {
+			var (
+				srv      = NewRPC()
+				finished = make(chan struct{})
+			)
+			require.NoError(t, srv.AddSubscription("counter", func(ctx context.Context, from int) (<-chan int, error) {
+				ch := make(chan int)
+				go func() {
+					defer close(finished)
+					for i := from; ; i++ {
+						select {
+						case ch <- i:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}()
+				return ch, nil
+			}, SubscriptionConfig{}))
+
+			st, closer := serveTestStream(srv, ConnConfig{})
+			defer closer()
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "counter", "params": 10}`)
+
+			res := new(wsResponse)
+			st.response(t, res)
+			require.Nil(t, res.Error)
+
+			var id string
+			require.NoError(t, json.Unmarshal(res.Result, &id))
+
+			for i := 10; i < 13; i++ {
+				n := new(testNotification)
+				st.response(t, n)
+				require.Equal(t, SubscriptionMethod, n.Method)
+				require.Equal(t, id, n.Params.Subscription)
+				require.Equal(t, string(mustMarshal(t, i)), string(n.Params.Result))
+			}
+
+			go st.request(t, `{"jsonrpc": "2.0", "id": 2, "method": "unsubscribe", "params": ["`+id+`"]}`)
+
+			// Skip notifications sent before unsubscribe.
+			for {
+				res = new(wsResponse)
+				st.response(t, res)
+				if res.ID != nil {
+					break
+				}
+			}
+			require.Equal(t, "2", res.ID.String())
+			require.Equal(t, `true`, string(res.Result))
+
+			select {
+			case <-finished:
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "producer wasn't stopped")
+			}
+		})
+
+		t.Run("should end when source is closed", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddSubscription("once", func(ctx context.Context, args struct{}) (<-chan string, error) {
+				ch := make(chan string, 1)
+				ch <- "item"
+				close(ch)
+				return ch, nil
+			}, SubscriptionConfig{}))
+
+			st, closer := serveTestStream(srv, ConnConfig{})
+			defer closer()
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "once"}`)
+			st.response(t, new(wsResponse))
+
+			n := new(testNotification)
+			st.response(t, n)
+			require.Equal(t, `"item"`, string(n.Params.Result))
+
+			// Subscription is removed asynchronously.
+			for i := 0; ; i++ {
+				st.request(t, `{"jsonrpc": "2.0", "id": 2, "method": "unsubscribe", "params": ["1"]}`)
+				res := new(wsResponse)
+				st.response(t, res)
+				if string(res.Result) == `false` {
+					break
+				}
+				require.True(t, i < 100, "subscription wasn't removed")
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+
+		t.Run("should disconnect slow client", func(t *testing.T) {
+			var (
+				srv      = NewRPC()
+				finished = make(chan struct{})
+			)
+			require.NoError(t, srv.AddSubscription("flood", func(ctx context.Context, args struct{}) (<-chan int, error) {
+				ch := make(chan int)
+				go func() {
+					defer close(finished)
+					for i := 0; ; i++ {
+						select {
+						case ch <- i:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}()
+				return ch, nil
+			}, SubscriptionConfig{Buffer: 1, Overflow: OverflowDisconnect}))
+
+			st, closer := serveTestStream(srv, ConnConfig{SendQueue: 1})
+			defer closer()
+
+			st.request(t, `{"jsonrpc": "2.0", "id": 1, "method": "flood"}`)
+
+			// Client doesn't read anything, so the connection is closed.
+			select {
+			case <-finished:
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "connection wasn't closed")
+			}
+		})
+
+		t.Run("should fail without persistent connection", func(t *testing.T) {
+			var srv = newTestRPC()
+			require.NoError(t, srv.AddSubscription("sub", func(ctx context.Context, args struct{}) (<-chan int, error) {
+				return make(chan int), nil
+			}, SubscriptionConfig{}))
+
+			res := serveTestRequest(t, srv, `{"jsonrpc": "2.0", "id": 1, "method": "sub"}`)
+			require.NotNil(t, res.Error)
+			require.Equal(t, ErrNoConnection.Message, res.Error.Message)
+
+			err := srv.Call(context.Background(), "sub", nil, nil)
+			require.Equal(t, ErrNoConnection, err)
+		})
+
+		t.Run("should fail on bad subscription", func(t *testing.T) {
+			var srv = NewRPC()
+			for _, fn := range []interface{}{
+				new(int),
+				func(ctx context.Context) (<-chan int, error) { return nil, nil },
+				func(ctx context.Context, args int) (chan<- int, error) { return nil, nil },
+				func(ctx context.Context, args int) (<-chan int, int) { return nil, 0 },
+				func(args, b int) (<-chan int, error) { return nil, nil },
+			} {
+				require.Equal(t, ErrSubscriptionSignature, srv.AddSubscription("sub", fn, SubscriptionConfig{}))
+			}
+		})
+	})
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
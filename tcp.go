@@ -0,0 +1,108 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"time"
+)
+
+// lineStream frames messages with newlines (NDJSON).
+type lineStream struct {
+	rwc   io.ReadWriteCloser
+	r     *bufio.Reader
+	limit int64
+}
+
+const (
+	//ErrMessageTooLarge when received message exceeds read limit
+	ErrMessageTooLarge = Error("message is too large")
+)
+
+// newLineStream returns stream of newline-delimited messages.
+func newLineStream(rwc io.ReadWriteCloser, limit int64) *lineStream {
+	return &lineStream{
+		rwc:   rwc,
+		r:     bufio.NewReader(rwc),
+		limit: limit,
+	}
+}
+
+// Serve accepts connections on the listener and serves newline-delimited
+// messages received with them, see ServeConn. It returns when listener
+// fails, e.g. when it's closed.
+func (s *RPC) Serve(l net.Listener, cfg ConnConfig) error {
+	return accept(l, func(nc net.Conn) {
+		s.ServeConn(context.Background(), nc, cfg)
+	})
+}
+
+// accept accepts connections and serves each of them in a new goroutine.
+func accept(l net.Listener, serve func(nc net.Conn)) error {
+	var delay time.Duration
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			// Retry temporary errors the same way net/http does.
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+
+		go serve(nc)
+	}
+}
+
+// ServeConn serves newline-delimited messages (NDJSON) of the connection
+// until it's closed. Messages are served concurrently, responses are written
+// in completion order.
+func (s *RPC) ServeConn(ctx context.Context, rwc io.ReadWriteCloser, cfg ConnConfig) {
+	cfg = cfg.withDefaults()
+	s.newConn(ctx, newLineStream(rwc, cfg.ReadLimit), cfg).serve()
+}
+
+// ReadMessage reads the next non-empty line.
+func (s *lineStream) ReadMessage() ([]byte, error) {
+	for {
+		var msg []byte
+		for {
+			line, err := s.r.ReadSlice('\n')
+			msg = append(msg, line...)
+			if int64(len(msg)) > s.limit {
+				return nil, ErrMessageTooLarge
+			} else if err == bufio.ErrBufferFull {
+				continue
+			} else if err == io.EOF && len(bytes.TrimSpace(msg)) != 0 {
+				break // the last line isn't terminated
+			} else if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		if msg = bytes.TrimSpace(msg); len(msg) != 0 {
+			return msg, nil
+		}
+	}
+}
+
+// WriteMessage writes message followed by newline.
+func (s *lineStream) WriteMessage(msg []byte) error {
+	_, err := s.rwc.Write(append(msg, '\n'))
+	return err
+}
+
+// Close closes the underlying connection.
+func (s *lineStream) Close() error {
+	return s.rwc.Close()
+}
@@ -0,0 +1,122 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTCP(t *testing.T, srv *RPC, cfg ConnConfig) (*net.TCPConn, *bufio.Scanner, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l, cfg) }()
+
+	nc, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, nc.SetDeadline(time.Now().Add(5*time.Second)))
+
+	return nc.(*net.TCPConn), bufio.NewScanner(nc), func() {
+		_ = nc.Close()
+		_ = l.Close()
+		require.Error(t, <-done)
+	}
+}
+
+func readTCPResponse(t *testing.T, sc *bufio.Scanner, res interface{}) {
+	require.True(t, sc.Scan(), "response wasn't read")
+	require.NoError(t, json.Unmarshal(sc.Bytes(), res))
+}
+
+func TestTCPSuite(t *testing.T) {
+	t.Run("TCP test suite", func(t *testing.T) {
+		t.Run("should serve newline-delimited messages", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			nc, sc, closer := newTestTCP(t, srv, ConnConfig{})
+			defer closer()
+
+			_, err := nc.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}` + "\n\n"))
+			require.NoError(t, err)
+
+			res := new(wsResponse)
+			readTCPResponse(t, sc, res)
+			require.Equal(t, `3`, string(res.Result))
+
+			_, err = nc.Write([]byte(`[{"jsonrpc": "2.0", "id": 2, "method": "sum", "params": [3]}]` + "\n"))
+			require.NoError(t, err)
+
+			var batch []wsResponse
+			readTCPResponse(t, sc, &batch)
+			require.Len(t, batch, 1)
+			require.Equal(t, `3`, string(batch[0].Result))
+		})
+
+		t.Run("should write responses in completion order", func(t *testing.T) {
+			var (
+				srv     = NewRPC()
+				release = make(chan struct{})
+			)
+			require.NoError(t, srv.AddMethod("wait", func(ctx context.Context, args struct{}, reply *string) error {
+				<-release
+				*reply = "wait"
+				return nil
+			}))
+			require.NoError(t, srv.AddMethod("release", func(ctx context.Context, args struct{}, reply *string) error {
+				close(release)
+				*reply = "release"
+				return nil
+			}))
+
+			nc, sc, closer := newTestTCP(t, srv, ConnConfig{})
+			defer closer()
+
+			_, err := nc.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "wait"}` + "\n" +
+				`{"jsonrpc": "2.0", "id": 2, "method": "release"}` + "\n"))
+			require.NoError(t, err)
+
+			first, second := new(wsResponse), new(wsResponse)
+			readTCPResponse(t, sc, first)
+			readTCPResponse(t, sc, second)
+			require.Equal(t, `"release"`, string(first.Result))
+			require.Equal(t, `"wait"`, string(second.Result))
+		})
+
+		t.Run("should respond after client stops writing", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			nc, sc, closer := newTestTCP(t, srv, ConnConfig{})
+			defer closer()
+
+			// The last line isn't terminated.
+			_, err := nc.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`))
+			require.NoError(t, err)
+			require.NoError(t, nc.CloseWrite())
+
+			res := new(wsResponse)
+			readTCPResponse(t, sc, res)
+			require.Equal(t, `3`, string(res.Result))
+			require.False(t, sc.Scan())
+		})
+
+		t.Run("should close connection on too large message", func(t *testing.T) {
+			var srv = NewRPC()
+
+			nc, sc, closer := newTestTCP(t, srv, ConnConfig{ReadLimit: 16})
+			defer closer()
+
+			_, err := nc.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "` + strings.Repeat("a", 4096) + `"}` + "\n"))
+			require.NoError(t, err)
+			require.False(t, sc.Scan())
+		})
+	})
+}
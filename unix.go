@@ -0,0 +1,103 @@
+package jsonrpc
+
+import (
+	"context"
+	"net"
+)
+
+type (
+	// Credentials of the process on the other side of Unix socket.
+	Credentials struct {
+		PID int32
+		UID uint32
+		GID uint32
+	}
+
+	// credentialsKey is the context key of peer credentials.
+	credentialsKey struct{}
+
+	// packetStream reads messages from SOCK_SEQPACKET socket, one message
+	// per packet.
+	packetStream struct {
+		conn  *net.UnixConn
+		limit int64
+		buf   []byte // read buffer, reused for every packet
+	}
+)
+
+const (
+	//ErrNoCredentials when platform doesn't provide peer credentials
+	ErrNoCredentials = Error("peer credentials aren't supported on this platform")
+)
+
+// ServeUnix accepts connections on Unix socket listener and serves messages
+// received with them. Stream sockets ("unix" network) use newline-delimited
+// framing, seqpacket ones ("unixpacket") carry one message per packet.
+// Credentials of the connected process are available to handlers and
+// middleware with CredentialsFromContext, when the platform supports it.
+func (s *RPC) ServeUnix(l *net.UnixListener, cfg ConnConfig) error {
+	cfg = cfg.withDefaults()
+	packet := l.Addr().Network() == "unixpacket"
+
+	return accept(l, func(nc net.Conn) {
+		var (
+			uc  = nc.(*net.UnixConn)
+			ctx = context.Background()
+			st  stream
+		)
+
+		if cred, err := peerCredentials(uc); err == nil {
+			ctx = context.WithValue(ctx, credentialsKey{}, cred)
+		}
+
+		if packet {
+			st = &packetStream{conn: uc, limit: cfg.ReadLimit}
+		} else {
+			st = newLineStream(uc, cfg.ReadLimit)
+		}
+
+		s.newConn(ctx, st, cfg).serve()
+	})
+}
+
+// CredentialsFromContext returns credentials of the process connected with
+// Unix socket the call came with, false is returned for other transports or
+// when credentials aren't available.
+func CredentialsFromContext(ctx context.Context) (Credentials, bool) {
+	cred, ok := ctx.Value(credentialsKey{}).(*Credentials)
+	if !ok {
+		return Credentials{}, false
+	}
+	return *cred, true
+}
+
+// ReadMessage reads the next non-empty packet, it's copied from the read
+// buffer, so the buffer is reused.
+func (s *packetStream) ReadMessage() ([]byte, error) {
+	if s.buf == nil {
+		// One byte more to detect truncated packets.
+		s.buf = make([]byte, s.limit+1)
+	}
+
+	for {
+		n, err := s.conn.Read(s.buf)
+		if err != nil {
+			return nil, err
+		} else if int64(n) > s.limit {
+			return nil, ErrMessageTooLarge
+		} else if n != 0 {
+			return append([]byte(nil), s.buf[:n]...), nil
+		}
+	}
+}
+
+// WriteMessage writes message as a single packet.
+func (s *packetStream) WriteMessage(msg []byte) error {
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+// Close closes the underlying connection.
+func (s *packetStream) Close() error {
+	return s.conn.Close()
+}
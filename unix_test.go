@@ -0,0 +1,138 @@
+//go:build linux
+// +build linux
+
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUnix(t *testing.T, srv *RPC, network string) (net.Conn, func()) {
+	dir, err := ioutil.TempDir("", "jsonrpc")
+	require.NoError(t, err)
+
+	l, err := net.ListenUnix(network, &net.UnixAddr{Name: filepath.Join(dir, "rpc.sock"), Net: network})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.ServeUnix(l, ConnConfig{}) }()
+
+	nc, err := net.Dial(network, l.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, nc.SetDeadline(time.Now().Add(5*time.Second)))
+
+	return nc, func() {
+		_ = nc.Close()
+		_ = l.Close()
+		require.Error(t, <-done)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestUnixSuite(t *testing.T) {
+	t.Run("Unix socket test suite", func(t *testing.T) {
+		newRPC := func(t *testing.T) *RPC {
+			srv := NewRPC()
+			require.NoError(t, srv.AddMethod("whoami", func(ctx context.Context, args struct{}, reply *Credentials) error {
+				cred, ok := CredentialsFromContext(ctx)
+				if !ok {
+					return ErrNoCredentials
+				}
+				*reply = cred
+				return nil
+			}))
+			return srv
+		}
+
+		self := Credentials{
+			PID: int32(os.Getpid()),
+			UID: uint32(os.Getuid()),
+			GID: uint32(os.Getgid()),
+		}
+
+		t.Run("should pass credentials over stream socket", func(t *testing.T) {
+			nc, closer := newTestUnix(t, newRPC(t), "unix")
+			defer closer()
+
+			_, err := nc.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "whoami"}` + "\n"))
+			require.NoError(t, err)
+
+			res := new(wsResponse)
+			require.NoError(t, json.NewDecoder(nc).Decode(res))
+			require.Nil(t, res.Error)
+
+			var cred Credentials
+			require.NoError(t, json.Unmarshal(res.Result, &cred))
+			require.Equal(t, self, cred)
+		})
+
+		t.Run("should serve seqpacket socket", func(t *testing.T) {
+			nc, closer := newTestUnix(t, newRPC(t), "unixpacket")
+			defer closer()
+
+			_, err := nc.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "whoami"}`))
+			require.NoError(t, err)
+
+			buf := make([]byte, 1024)
+			n, err := nc.Read(buf)
+			require.NoError(t, err)
+
+			res := new(wsResponse)
+			require.NoError(t, json.Unmarshal(buf[:n], res))
+			require.Nil(t, res.Error)
+
+			var cred Credentials
+			require.NoError(t, json.Unmarshal(res.Result, &cred))
+			require.Equal(t, self, cred)
+		})
+
+		t.Run("should read packets into reused buffer", func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "jsonrpc")
+			require.NoError(t, err)
+			defer func() { _ = os.RemoveAll(dir) }()
+
+			l, err := net.ListenUnix("unixpacket", &net.UnixAddr{Name: filepath.Join(dir, "rpc.sock"), Net: "unixpacket"})
+			require.NoError(t, err)
+			defer func() { _ = l.Close() }()
+
+			nc, err := net.Dial("unixpacket", l.Addr().String())
+			require.NoError(t, err)
+			defer func() { _ = nc.Close() }()
+
+			uc, err := l.AcceptUnix()
+			require.NoError(t, err)
+
+			st := &packetStream{conn: uc, limit: 8}
+			defer func() { _ = st.Close() }()
+
+			for _, msg := range []string{"first", "second", "too large"} {
+				_, err = nc.Write([]byte(msg))
+				require.NoError(t, err)
+			}
+
+			first, err := st.ReadMessage()
+			require.NoError(t, err)
+			second, err := st.ReadMessage()
+			require.NoError(t, err)
+			require.Equal(t, "first", string(first))
+			require.Equal(t, "second", string(second))
+
+			_, err = st.ReadMessage()
+			require.Equal(t, ErrMessageTooLarge, err)
+		})
+
+		t.Run("should not have credentials over other transports", func(t *testing.T) {
+			var reply Credentials
+			require.Equal(t, ErrNoCredentials, newRPC(t).Call(context.Background(), "whoami", nil, &reply))
+		})
+	})
+}
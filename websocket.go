@@ -0,0 +1,151 @@
+package jsonrpc
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nspcc-dev/jsonrpc/codec"
+)
+
+type (
+	// WebSocketConfig configures WebSocket transport.
+	WebSocketConfig struct {
+		ConnConfig
+
+		// PingInterval is the period of pings sent to the client.
+		PingInterval time.Duration
+
+		// PongTimeout is the time connection is kept open without pongs
+		// (or any other messages) received, it must exceed PingInterval.
+		PongTimeout time.Duration
+
+		// WriteTimeout is the time limit of a single message write.
+		WriteTimeout time.Duration
+
+		// CheckOrigin returns true if the request Origin header is
+		// acceptable, same origin policy is applied when it's nil.
+		CheckOrigin func(r *http.Request) bool
+	}
+
+	// webSocket serves registered methods over WebSocket connections.
+	webSocket struct {
+		rpc      *RPC
+		cfg      WebSocketConfig
+		upgrader websocket.Upgrader
+	}
+
+	// wsStream adapts WebSocket connection to stream.
+	wsStream struct {
+		conn         *websocket.Conn
+		readTimeout  time.Duration
+		writeTimeout time.Duration
+		kind         int // type of written messages
+	}
+)
+
+const (
+	// DefaultWSPingInterval is used when WebSocketConfig.PingInterval isn't set.
+	DefaultWSPingInterval = 30 * time.Second
+	// DefaultWSPongTimeout is used when WebSocketConfig.PongTimeout isn't set.
+	DefaultWSPongTimeout = 60 * time.Second
+	// DefaultWSWriteTimeout is used when WebSocketConfig.WriteTimeout isn't set.
+	DefaultWSWriteTimeout = 10 * time.Second
+)
+
+// WebSocket returns http.Handler serving registered methods over WebSocket.
+// Messages received within a connection are served concurrently, responses
+// are written in completion order.
+func (s *RPC) WebSocket(cfg WebSocketConfig) http.Handler {
+	cfg.ConnConfig = cfg.ConnConfig.withDefaults()
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = DefaultWSPingInterval
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = DefaultWSPongTimeout
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = DefaultWSWriteTimeout
+	}
+
+	return &webSocket{
+		rpc: s,
+		cfg: cfg,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: cfg.CheckOrigin,
+		},
+	}
+}
+
+// ServeHTTP upgrades the request and serves the connection until it's closed.
+func (ws *webSocket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Upgrader replies with HTTP error itself.
+	wc, err := ws.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	wc.SetReadLimit(ws.cfg.ReadLimit)
+	_ = wc.SetReadDeadline(time.Now().Add(ws.cfg.PongTimeout))
+	wc.SetPongHandler(func(string) error {
+		return wc.SetReadDeadline(time.Now().Add(ws.cfg.PongTimeout))
+	})
+
+	st := &wsStream{
+		conn:         wc,
+		readTimeout:  ws.cfg.PongTimeout,
+		writeTimeout: ws.cfg.WriteTimeout,
+		kind:         websocket.TextMessage,
+	}
+	if bc, ok := ws.cfg.Codec.(codec.BinaryCodec); ok && bc.Binary() {
+		st.kind = websocket.BinaryMessage
+	}
+
+	c := ws.rpc.newConn(withRequest(r.Context(), r), st, ws.cfg.ConnConfig)
+
+	go ws.ping(c)
+	c.serve()
+}
+
+// ping sends pings until the connection is closed.
+func (ws *webSocket) ping(c *conn) {
+	ticker := time.NewTicker(ws.cfg.PingInterval)
+	defer ticker.Stop()
+
+	wc := c.stream.(*wsStream).conn
+	for {
+		select {
+		case <-ticker.C:
+			deadline := time.Now().Add(ws.cfg.WriteTimeout)
+			if err := wc.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				c.close()
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// ReadMessage reads the next data message, extending read deadline.
+func (s *wsStream) ReadMessage() ([]byte, error) {
+	_, msg, err := s.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	return msg, s.conn.SetReadDeadline(time.Now().Add(s.readTimeout))
+}
+
+// WriteMessage writes text message, messages of codecs implementing
+// codec.BinaryCodec, e.g. MessagePack, are written as binary ones.
+func (s *wsStream) WriteMessage(msg []byte) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.writeTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteMessage(s.kind, msg)
+}
+
+// Close closes the underlying connection.
+func (s *wsStream) Close() error {
+	return s.conn.Close()
+}
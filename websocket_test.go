@@ -0,0 +1,183 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nspcc-dev/jsonrpc/codec"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	wsResponse struct {
+		ID *json.Number `json:"id"`
+		testResponse
+	}
+
+	// binaryCodec is JSON codec declaring its messages binary.
+	binaryCodec struct {
+		codec.MessageCodec
+	}
+)
+
+func (binaryCodec) Binary() bool { return true }
+
+func newTestWebSocket(t *testing.T, srv *RPC, cfg WebSocketConfig) (*websocket.Conn, func()) {
+	ts := httptest.NewServer(srv.WebSocket(cfg))
+
+	wc, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	return wc, func() {
+		_ = wc.Close()
+		ts.Close()
+	}
+}
+
+func readWSResponse(t *testing.T, wc *websocket.Conn, res interface{}) {
+	require.NoError(t, wc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, msg, err := wc.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(msg, res))
+}
+
+func TestWebSocketSuite(t *testing.T) {
+	t.Run("WebSocket test suite", func(t *testing.T) {
+		t.Run("should serve requests, batches and notifications", func(t *testing.T) {
+			var (
+				srv      = NewRPC()
+				notified = make(chan int, 1)
+			)
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+			require.NoError(t, srv.AddMethod("notify", func(ctx context.Context, args int, reply *struct{}) error {
+				notified <- args
+				return nil
+			}))
+
+			wc, closer := newTestWebSocket(t, srv, WebSocketConfig{})
+			defer closer()
+
+			require.NoError(t, wc.WriteMessage(websocket.TextMessage,
+				[]byte(`{"jsonrpc": "2.0", "method": "notify", "params": 5}`)))
+			require.Equal(t, 5, <-notified)
+
+			require.NoError(t, wc.WriteMessage(websocket.TextMessage,
+				[]byte(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`)))
+
+			res := new(wsResponse)
+			readWSResponse(t, wc, res)
+			require.Equal(t, "1", res.ID.String())
+			require.Equal(t, `3`, string(res.Result))
+
+			require.NoError(t, wc.WriteMessage(websocket.TextMessage, []byte(`[
+				{"jsonrpc": "2.0", "id": 2, "method": "sum", "params": [1]},
+				{"jsonrpc": "2.0", "id": 3, "method": "sum", "params": [2]}
+			]`)))
+
+			var batch []wsResponse
+			readWSResponse(t, wc, &batch)
+			require.Len(t, batch, 2)
+			require.Equal(t, `1`, string(batch[0].Result))
+			require.Equal(t, `2`, string(batch[1].Result))
+		})
+
+		t.Run("should write message type declared by codec", func(t *testing.T) {
+			var srv = NewRPC()
+			require.NoError(t, srv.AddMethod("sum", sumMethod))
+
+			for cdc, kind := range map[codec.MessageCodec]int{
+				codec.NewCodec():              websocket.TextMessage,
+				binaryCodec{codec.NewCodec()}: websocket.BinaryMessage,
+			} {
+				wc, closer := newTestWebSocket(t, srv, WebSocketConfig{ConnConfig: ConnConfig{Codec: cdc}})
+
+				require.NoError(t, wc.WriteMessage(websocket.TextMessage,
+					[]byte(`{"jsonrpc": "2.0", "id": 1, "method": "sum", "params": [1,2]}`)))
+				require.NoError(t, wc.SetReadDeadline(time.Now().Add(5*time.Second)))
+				typ, msg, err := wc.ReadMessage()
+				require.NoError(t, err)
+				require.Equal(t, kind, typ)
+				require.True(t, json.Valid(msg))
+				closer()
+			}
+		})
+
+		t.Run("should serve messages concurrently", func(t *testing.T) {
+			var (
+				srv     = NewRPC()
+				release = make(chan struct{})
+			)
+			require.NoError(t, srv.AddMethod("wait", func(ctx context.Context, args struct{}, reply *string) error {
+				<-release
+				*reply = "wait"
+				return nil
+			}))
+			require.NoError(t, srv.AddMethod("release", func(ctx context.Context, args struct{}, reply *string) error {
+				close(release)
+				*reply = "release"
+				return nil
+			}))
+
+			wc, closer := newTestWebSocket(t, srv, WebSocketConfig{})
+			defer closer()
+
+			require.NoError(t, wc.WriteMessage(websocket.TextMessage,
+				[]byte(`{"jsonrpc": "2.0", "id": 1, "method": "wait"}`)))
+			require.NoError(t, wc.WriteMessage(websocket.TextMessage,
+				[]byte(`{"jsonrpc": "2.0", "id": 2, "method": "release"}`)))
+
+			first, second := new(wsResponse), new(wsResponse)
+			readWSResponse(t, wc, first)
+			readWSResponse(t, wc, second)
+			require.Equal(t, `"release"`, string(first.Result))
+			require.Equal(t, `"wait"`, string(second.Result))
+		})
+
+		t.Run("should send pings", func(t *testing.T) {
+			var (
+				srv  = NewRPC()
+				ping = make(chan struct{}, 1)
+			)
+
+			wc, closer := newTestWebSocket(t, srv, WebSocketConfig{
+				PingInterval: 10 * time.Millisecond,
+			})
+			defer closer()
+
+			wc.SetPingHandler(func(string) error {
+				select {
+				case ping <- struct{}{}:
+				default:
+				}
+				return nil
+			})
+			go func() { _, _, _ = wc.ReadMessage() }()
+
+			select {
+			case <-ping:
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "ping wasn't received")
+			}
+		})
+
+		t.Run("should close connection without pongs", func(t *testing.T) {
+			var srv = NewRPC()
+
+			wc, closer := newTestWebSocket(t, srv, WebSocketConfig{
+				PingInterval: 10 * time.Millisecond,
+				PongTimeout:  50 * time.Millisecond,
+			})
+			defer closer()
+
+			wc.SetPingHandler(func(string) error { return nil })
+
+			require.NoError(t, wc.SetReadDeadline(time.Now().Add(5*time.Second)))
+			_, _, err := wc.ReadMessage()
+			require.Error(t, err)
+		})
+	})
+}